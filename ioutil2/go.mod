@@ -0,0 +1,3 @@
+module github.com/msolo/go-bis/ioutil2
+
+go 1.13
@@ -47,7 +47,13 @@ func (afw *atomicFileWriter) Close() (err error) {
 		return err
 	}
 
-	fDir, err := os.Open(filepath.Dir(afw.dstFilename))
+	return SyncDir(filepath.Dir(afw.dstFilename))
+}
+
+// SyncDir flushes a directory so recently created, renamed or removed
+// entries in it survive a crash.
+func SyncDir(dir string) (err error) {
+	fDir, err := os.Open(dir)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,78 @@
+package slog
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// AsyncHandler moves writes off the calling goroutine. When the queue is
+// full entries are dropped rather than blocking the caller.
+type AsyncHandler struct {
+	mu      sync.RWMutex
+	h       Handler
+	ch      chan Entry
+	done    chan struct{}
+	closed  bool
+	dropped uint64
+}
+
+// NewAsyncHandler queues up to size entries for h.
+func NewAsyncHandler(h Handler, size int) *AsyncHandler {
+	ah := &AsyncHandler{
+		h:    h,
+		ch:   make(chan Entry, size),
+		done: make(chan struct{}),
+	}
+	go ah.run()
+	return ah
+}
+
+func (ah *AsyncHandler) run() {
+	defer close(ah.done)
+	for e := range ah.ch {
+		if err := ah.h.WriteEntry(e); err != nil {
+			println("log write failed:", err.Error())
+		}
+	}
+}
+
+func (ah *AsyncHandler) Enabled(level Level, logger string) bool {
+	return handlerEnabled(ah.h, level, logger)
+}
+
+func (ah *AsyncHandler) wrappedHandlers() []Handler {
+	return []Handler{ah.h}
+}
+
+func (ah *AsyncHandler) WriteEntry(e Entry) error {
+	ah.mu.RLock()
+	defer ah.mu.RUnlock()
+	if ah.closed {
+		atomic.AddUint64(&ah.dropped, 1)
+		return nil
+	}
+	select {
+	case ah.ch <- e:
+	default:
+		atomic.AddUint64(&ah.dropped, 1)
+	}
+	return nil
+}
+
+// Dropped returns the number of entries discarded because the queue was
+// full or the handler was closed.
+func (ah *AsyncHandler) Dropped() uint64 {
+	return atomic.LoadUint64(&ah.dropped)
+}
+
+// Close stops accepting entries and waits for the queue to drain.
+func (ah *AsyncHandler) Close() error {
+	ah.mu.Lock()
+	if !ah.closed {
+		ah.closed = true
+		close(ah.ch)
+	}
+	ah.mu.Unlock()
+	<-ah.done
+	return nil
+}
@@ -0,0 +1,378 @@
+package slog
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/msolo/go-bis/ioutil2"
+)
+
+// Audit logs are JSON lines. Each file starts with a header holding the
+// sequence number and the MAC of the record before it, signed with
+// HMAC-SHA256. Each record holds its sequence number, the HMAC-SHA256 of
+// the previous record's line, which itself includes the MAC before it,
+// and the entry:
+//
+//	{"audit":1,"seq":1,"prev":"0000…","created":"…","mac":"…"}
+//	{"seq":1,"prev":"0000…","entry":{"Level":"info",…}}
+//	{"seq":2,"prev":"<hmac of the line above>","entry":{…}}
+//
+// Changing, removing or reordering records breaks the chain, and mending
+// it requires the key. The last
+// sequence number and MAC, the chain head, are saved in a signed file
+// next to the log whenever a file is started and on Close, so truncation
+// back past that point is detected too, by VerifyAudit and when the log
+// is reopened.
+//
+// Records written since the chain head was last saved are not covered by
+// it: removing them from the end of the log goes undetected. Saving the
+// head on every write would cost an extra durable file replacement per
+// entry, so this is a limit of the design; Close the handler to cover
+// everything written.
+const auditVersion = 1
+
+var genesisHash = strings.Repeat("0", sha256.Size*2)
+
+type auditHeader struct {
+	Audit   int       `json:"audit"`
+	Seq     uint64    `json:"seq"`
+	Prev    string    `json:"prev"`
+	Created time.Time `json:"created"`
+	Mac     string    `json:"mac"`
+}
+
+type auditRecord struct {
+	Seq   uint64          `json:"seq"`
+	Prev  string          `json:"prev"`
+	Entry json.RawMessage `json:"entry"`
+}
+
+// auditLine is either a header or a record.
+type auditLine struct {
+	auditHeader
+	Entry json.RawMessage `json:"entry"`
+}
+
+type auditHead struct {
+	Seq  uint64 `json:"seq"`
+	Hash string `json:"hash"`
+	Mac  string `json:"mac"`
+}
+
+func auditMac(key []byte, parts ...interface{}) string {
+	mac := hmac.New(sha256.New, key)
+	fmt.Fprintln(mac, parts...)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (h *auditHeader) mac(key []byte) string {
+	return auditMac(key, h.Audit, h.Seq, h.Prev, h.Created.UnixNano())
+}
+
+func (h *auditHead) mac(key []byte) string {
+	return auditMac(key, h.Seq, h.Hash)
+}
+
+// recordMac chains a record to the ones before it: the line holds the
+// previous record's MAC, so this covers the whole chain up to line.
+func recordMac(key, line []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(line)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func headPath(path string) string {
+	return path + ".head"
+}
+
+func readAuditHead(path string, key []byte) (*auditHead, error) {
+	data, err := ioutil.ReadFile(headPath(path))
+	if err != nil {
+		return nil, err
+	}
+	head := &auditHead{}
+	if err := json.Unmarshal(data, head); err != nil {
+		return nil, fmt.Errorf("invalid audit chain head %s: %v", headPath(path), err)
+	}
+	if !hmac.Equal([]byte(head.Mac), []byte(head.mac(key))) {
+		return nil, fmt.Errorf("audit chain head %s has a bad signature", headPath(path))
+	}
+	return head, nil
+}
+
+// AuditHandler appends entries to a hash-chained, tamper-evident log. It
+// writes through a FileHandler, so rotation and durability follow its
+// FileConfig; SyncAlways makes every entry durable before WriteEntry
+// returns. Check logs with VerifyAudit.
+type AuditHandler struct {
+	mu   sync.Mutex
+	fh   *FileHandler
+	path string
+	key  []byte
+	// The last record written, or the chain head a new file continues.
+	seq  uint64
+	hash string
+	// The hash of the record being written.
+	pending string
+}
+
+// OpenAuditHandler opens or continues the audit log at path, signing with
+// key. It fails if the log no longer reaches the saved chain head. A
+// partial record left by a crash is removed, with a warning on stderr, as
+// it was never acknowledged.
+func OpenAuditHandler(path string, key []byte, cfg FileConfig) (*AuditHandler, error) {
+	ah := &AuditHandler{path: path, key: key, hash: genesisHash}
+	head, err := readAuditHead(path, key)
+	if err == nil {
+		ah.seq, ah.hash = head.Seq, head.Hash
+	} else if os.IsNotExist(err) {
+		head = nil
+	} else {
+		return nil, err
+	}
+	if err := ah.resume(head); err != nil {
+		return nil, err
+	}
+	cfg.Rotate.Header = ah.header
+	fh, err := OpenFileHandler(path, ah.fmtRecord, cfg)
+	if err != nil {
+		return nil, err
+	}
+	ah.fh = fh
+	return ah, nil
+}
+
+// resume picks up the chain from the last complete line of the newest
+// file with any content, which is a backup if a rotation was interrupted,
+// and checks that it reaches the chain head.
+func (ah *AuditHandler) resume(head *auditHead) error {
+	files, err := filepath.Glob(ah.path + ".[0-9]*")
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+	files = append(files, ah.path)
+	var (
+		fname string
+		data  []byte
+	)
+	for i := len(files) - 1; i >= 0 && len(data) == 0; i-- {
+		fname = files[i]
+		if data, err = ioutil.ReadFile(fname); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if len(data) == 0 {
+		if head != nil && head.Seq > 0 {
+			return fmt.Errorf("audit log %s is missing; chain head is record %d", ah.path, head.Seq)
+		}
+		return nil
+	}
+
+	if end := bytes.LastIndexByte(data, '\n') + 1; end < len(data) {
+		println("audit log", fname+":", "removing partial record of", len(data)-end, "bytes")
+		if err := os.Truncate(fname, int64(end)); err != nil {
+			return err
+		}
+		data = data[:end]
+	}
+	found := head == nil
+	for _, line := range bytes.Split(bytes.TrimSuffix(data, []byte{'\n'}), []byte{'\n'}) {
+		al := &auditLine{}
+		if err := json.Unmarshal(line, al); err != nil {
+			return fmt.Errorf("invalid audit log %s: %v", fname, err)
+		}
+		if al.Audit != 0 {
+			ah.seq, ah.hash = al.Seq-1, al.Prev
+		} else {
+			ah.seq, ah.hash = al.Seq, recordMac(ah.key, line)
+		}
+		if head != nil && ah.seq == head.Seq {
+			if ah.hash != head.Hash {
+				return fmt.Errorf("audit log %s: record %d does not match the chain head", fname, ah.seq)
+			}
+			found = true
+		}
+	}
+	if !found {
+		if ah.seq < head.Seq {
+			return fmt.Errorf("audit log %s truncated: chain head is record %d, log ends at %d", fname, head.Seq, ah.seq)
+		}
+		return fmt.Errorf("audit log %s does not reach the chain head, record %d", fname, head.Seq)
+	}
+	return nil
+}
+
+// header signs the start of a new file and saves the chain head, which is
+// the last record of the previous file. It is called by the RotatingFile
+// while ah.mu is held, or during OpenAuditHandler.
+func (ah *AuditHandler) header() ([]byte, error) {
+	if err := ah.saveHead(); err != nil {
+		return nil, err
+	}
+	h := &auditHeader{Audit: auditVersion, Seq: ah.seq + 1, Prev: ah.hash, Created: time.Now().UTC()}
+	h.Mac = h.mac(ah.key)
+	data, err := json.Marshal(h)
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+func (ah *AuditHandler) saveHead() error {
+	head := &auditHead{Seq: ah.seq, Hash: ah.hash}
+	head.Mac = head.mac(ah.key)
+	data, err := json.Marshal(head)
+	if err != nil {
+		return err
+	}
+	return ioutil2.WriteFileAtomic(headPath(ah.path), append(data, '\n'), 0600)
+}
+
+// fmtRecord chains e to the previous record. It is called by the
+// FileHandler while ah.mu is held.
+func (ah *AuditHandler) fmtRecord(e Entry) string {
+	entryData, err := marshalEntryJSON(e)
+	if err != nil {
+		entryData, _ = json.Marshal(map[string]string{"JsonErr": err.Error()})
+	}
+	line, _ := json.Marshal(&auditRecord{Seq: ah.seq + 1, Prev: ah.hash, Entry: entryData})
+	ah.pending = recordMac(ah.key, line)
+	return string(append(line, '\n'))
+}
+
+func (ah *AuditHandler) WriteEntry(e Entry) error {
+	ah.mu.Lock()
+	defer ah.mu.Unlock()
+	if err := ah.fh.WriteEntry(e); err != nil {
+		// The record may be partially written; the chain can't continue
+		// past it safely, so make the failure loud.
+		return fmt.Errorf("audit log write failed: %v", err)
+	}
+	ah.seq++
+	ah.hash = ah.pending
+	return nil
+}
+
+// Rotate starts a new file immediately.
+func (ah *AuditHandler) Rotate() error {
+	ah.mu.Lock()
+	defer ah.mu.Unlock()
+	return ah.fh.Rotate()
+}
+
+// Close flushes the log and saves the chain head.
+func (ah *AuditHandler) Close() error {
+	ah.mu.Lock()
+	defer ah.mu.Unlock()
+	err := ah.fh.Close()
+	if headErr := ah.saveHead(); err == nil {
+		err = headErr
+	}
+	return err
+}
+
+// VerifyAudit checks the audit log at path and its rotated files against
+// key. It returns the number of records verified and an error describing
+// the first gap, edit, bad signature or truncation found. Backups removed
+// by MaxBackups are not reported; the chain is checked from the oldest
+// file remaining.
+func VerifyAudit(path string, key []byte) (int, error) {
+	head, err := readAuditHead(path, key)
+	if os.IsNotExist(err) {
+		head = nil
+	} else if err != nil {
+		return 0, err
+	}
+	files, err := filepath.Glob(path + ".[0-9]*")
+	if err != nil {
+		return 0, err
+	}
+	sort.Strings(files)
+	files = append(files, path)
+
+	var (
+		records int
+		seq     uint64
+		hash    string
+	)
+	for i, fname := range files {
+		f, err := os.Open(fname)
+		if err != nil {
+			return records, err
+		}
+		br := bufio.NewReader(f)
+		for lineno := 1; ; lineno++ {
+			line, err := br.ReadBytes('\n')
+			if err == io.EOF && len(line) == 0 {
+				break
+			} else if err == io.EOF {
+				f.Close()
+				return records, fmt.Errorf("%s:%d: truncated record", fname, lineno)
+			} else if err != nil {
+				f.Close()
+				return records, err
+			}
+			line = line[:len(line)-1]
+			al := &auditLine{}
+			if err := json.Unmarshal(line, al); err != nil {
+				f.Close()
+				return records, fmt.Errorf("%s:%d: invalid record: %v", fname, lineno, err)
+			}
+			if lineno == 1 {
+				if al.Audit != auditVersion {
+					f.Close()
+					return records, fmt.Errorf("%s:%d: missing audit header", fname, lineno)
+				}
+				if !hmac.Equal([]byte(al.Mac), []byte(al.auditHeader.mac(key))) {
+					f.Close()
+					return records, fmt.Errorf("%s:%d: bad header signature", fname, lineno)
+				}
+				if i == 0 {
+					seq, hash = al.Seq-1, al.Prev
+				} else if al.Seq != seq+1 || al.Prev != hash {
+					f.Close()
+					return records, fmt.Errorf("%s:%d: gap in chain: file starts at %d, previous file ended at %d", fname, lineno, al.Seq, seq)
+				}
+				continue
+			}
+			if al.Audit != 0 {
+				f.Close()
+				return records, fmt.Errorf("%s:%d: unexpected header", fname, lineno)
+			}
+			if al.Seq != seq+1 {
+				f.Close()
+				return records, fmt.Errorf("%s:%d: gap in chain: expected record %d, found %d", fname, lineno, seq+1, al.Seq)
+			}
+			if al.Prev != hash {
+				f.Close()
+				return records, fmt.Errorf("%s:%d: record %d does not follow record %d; it or an earlier one was altered", fname, lineno, al.Seq, seq)
+			}
+			seq, hash = al.Seq, recordMac(key, line)
+			if head != nil && seq == head.Seq && hash != head.Hash {
+				f.Close()
+				return records, fmt.Errorf("%s:%d: record %d does not match the chain head", fname, lineno, seq)
+			}
+			records++
+		}
+		f.Close()
+	}
+
+	if head != nil && head.Seq > seq {
+		return records, fmt.Errorf("log truncated: chain head is record %d, log ends at %d", head.Seq, seq)
+	}
+	return records, nil
+}
@@ -0,0 +1,190 @@
+package slog
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeAudit(t *testing.T, fname string, key []byte, n int) {
+	ah, err := OpenAuditHandler(fname, key, FileConfig{Sync: SyncAlways, Rotate: RotateConfig{MaxBytes: 1024}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	slog := &slogger{h: ah, cfg: &Config{}}
+	for i := 0; i < n; i++ {
+		slog.WithFields(Fields{"i": i}).Info("user logged in")
+	}
+	if err := ah.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAuditHandler(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	fname := filepath.Join(tmpDir, "audit.log")
+	key := []byte("secret")
+
+	writeAudit(t, fname, key, 20)
+	// Reopening continues the chain.
+	writeAudit(t, fname, key, 5)
+	if n, err := VerifyAudit(fname, key); err != nil || n != 25 {
+		t.Fatalf("verify: %d records, %v", n, err)
+	}
+	if _, err := VerifyAudit(fname, []byte("guess")); err == nil {
+		t.Error("wrong key accepted")
+	}
+
+	backups, _ := filepath.Glob(fname + ".[0-9]*")
+	sort.Strings(backups)
+	if len(backups) < 3 {
+		t.Fatalf("expected rotation, got %d backups", len(backups))
+	}
+
+	expectErr := func(what, want string) {
+		t.Helper()
+		_, err := VerifyAudit(fname, key)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: got %v, want %q", what, err, want)
+		}
+	}
+
+	// Edit a record.
+	data, _ := ioutil.ReadFile(backups[0])
+	edited := bytes.Replace(data, []byte(`"i":1}`), []byte(`"i":9}`), 1)
+	ioutil.WriteFile(backups[0], edited, 0600)
+	expectErr("edit", "altered")
+	ioutil.WriteFile(backups[0], data, 0600)
+
+	// Remove a file from the middle.
+	data, _ = ioutil.ReadFile(backups[1])
+	os.Remove(backups[1])
+	expectErr("gap", "gap in chain")
+	ioutil.WriteFile(backups[1], data, 0600)
+
+	// Drop records from the end.
+	data, _ = ioutil.ReadFile(fname)
+	lines := bytes.SplitAfter(data, []byte{'\n'})
+	ioutil.WriteFile(fname, bytes.Join(lines[:len(lines)-3], nil), 0600)
+	expectErr("truncation", "truncated")
+	ioutil.WriteFile(fname, data, 0600)
+
+	if _, err := VerifyAudit(fname, key); err != nil {
+		t.Errorf("restored log fails: %v", err)
+	}
+}
+
+func TestAuditResume(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	fname := filepath.Join(tmpDir, "audit.log")
+	key := []byte("secret")
+	open := func() error {
+		ah, err := OpenAuditHandler(fname, key, FileConfig{})
+		if err == nil {
+			err = ah.Close()
+		}
+		return err
+	}
+
+	writeAudit(t, fname, key, 20)
+	data, _ := ioutil.ReadFile(fname)
+	lines := bytes.SplitAfter(data, []byte{'\n'})
+
+	// Records removed back past the chain head.
+	ioutil.WriteFile(fname, bytes.Join(lines[:len(lines)-2], nil), 0600)
+	if err := open(); err == nil || !strings.Contains(err.Error(), "truncated") {
+		t.Errorf("truncation: got %v", err)
+	}
+
+	// The last record replaced.
+	forged := bytes.Replace(lines[len(lines)-2], []byte(`"i":19}`), []byte(`"i":7}`), 1)
+	ioutil.WriteFile(fname, append(bytes.Join(lines[:len(lines)-2], nil), forged...), 0600)
+	if err := open(); err == nil || !strings.Contains(err.Error(), "chain head") {
+		t.Errorf("forged record: got %v", err)
+	}
+
+	// A partial record from a crash is dropped and the chain continues.
+	ioutil.WriteFile(fname, append(data, `{"seq":99,"pr`...), 0600)
+	if err := open(); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := VerifyAudit(fname, key); err != nil || n != 20 {
+		t.Errorf("after partial record: %d records, %v", n, err)
+	}
+
+	// A rotation interrupted after renaming the file resumes from the
+	// backup.
+	if err := os.Rename(fname, fname+"."+time.Now().UTC().Format("20060102-150405.000000000")); err != nil {
+		t.Fatal(err)
+	}
+	writeAudit(t, fname, key, 3)
+	if n, err := VerifyAudit(fname, key); err != nil || n != 23 {
+		t.Errorf("after interrupted rotation: %d records, %v", n, err)
+	}
+
+	// The whole log removed, but not the chain head.
+	files, _ := filepath.Glob(fname + "*")
+	for _, f := range files {
+		if f != headPath(fname) {
+			os.Remove(f)
+		}
+	}
+	if err := open(); err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Errorf("removed log: got %v", err)
+	}
+}
+
+func TestAuditForgedChain(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	fname := filepath.Join(tmpDir, "audit.log")
+	key := []byte("secret")
+	ah, err := OpenAuditHandler(fname, key, FileConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	slog := &slogger{h: ah, cfg: &Config{}}
+	for i := 0; i < 5; i++ {
+		slog.WithFields(Fields{"amount": 1, "i": i}).Info("transfer")
+	}
+	// The handler stays open, as a live log would, so the chain head
+	// doesn't cover these records yet.
+
+	// Edit a record and rewrite every later prev without the key.
+	data, _ := ioutil.ReadFile(fname)
+	lines := bytes.Split(bytes.TrimSuffix(data, []byte{'\n'}), []byte{'\n'})
+	lines[2] = bytes.Replace(lines[2], []byte(`"amount":1`), []byte(`"amount":999`), 1)
+	for i := 3; i < len(lines); i++ {
+		rec := &auditRecord{}
+		if err := json.Unmarshal(lines[i], rec); err != nil {
+			t.Fatal(err)
+		}
+		sum := sha256.Sum256(lines[i-1])
+		rec.Prev = hex.EncodeToString(sum[:])
+		lines[i], _ = json.Marshal(rec)
+	}
+	ioutil.WriteFile(fname, append(bytes.Join(lines, []byte{'\n'}), '\n'), 0600)
+	if n, err := VerifyAudit(fname, key); err == nil || !strings.Contains(err.Error(), "altered") {
+		t.Errorf("forged chain verified: %d records, %v", n, err)
+	}
+	ah.Close()
+}
@@ -0,0 +1,443 @@
+package slog
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	stdErrors "errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"sort"
+	"time"
+)
+
+// Binary logs start with a file header, binaryMagic followed by a version
+// byte, then a sequence of records:
+//
+//	marker   2 bytes, binaryMarker
+//	length   uvarint, size of body
+//	body     length bytes
+//	crc      4 bytes, little endian CRC-32C of body
+//
+// The marker lets a reader find the next record after a torn write or
+// corruption. The body holds the entry's timestamp, level, hostname, pid,
+// source, message, error, stack frames and typed fields.
+const (
+	binaryMagic   = "SLOGBIN"
+	binaryVersion = 1
+	// Bodies larger than this are treated as corruption.
+	maxBinaryRecord = 1 << 20
+)
+
+var (
+	binaryMarker = [2]byte{0xb1, 0x06}
+	crcTable     = crc32.MakeTable(crc32.Castagnoli)
+)
+
+// Field value tags.
+const (
+	tagNil byte = iota
+	tagString
+	tagInt
+	tagUint
+	tagFloat
+	tagBool
+	tagTime
+	tagDuration
+	tagMap
+	tagList
+	tagJson
+)
+
+type binaryBuffer struct {
+	b []byte
+}
+
+func (bb *binaryBuffer) uvarint(x uint64) {
+	var tmp [binary.MaxVarintLen64]byte
+	bb.b = append(bb.b, tmp[:binary.PutUvarint(tmp[:], x)]...)
+}
+
+func (bb *binaryBuffer) varint(x int64) {
+	var tmp [binary.MaxVarintLen64]byte
+	bb.b = append(bb.b, tmp[:binary.PutVarint(tmp[:], x)]...)
+}
+
+func (bb *binaryBuffer) string(s string) {
+	bb.uvarint(uint64(len(s)))
+	bb.b = append(bb.b, s...)
+}
+
+func (bb *binaryBuffer) fields(f Fields) {
+	keys := make([]string, 0, len(f))
+	for k := range f {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	bb.uvarint(uint64(len(keys)))
+	for _, k := range keys {
+		bb.string(k)
+		bb.value(f[k])
+	}
+}
+
+func (bb *binaryBuffer) value(v interface{}) {
+	switch x := v.(type) {
+	case nil:
+		bb.b = append(bb.b, tagNil)
+	case string:
+		bb.b = append(bb.b, tagString)
+		bb.string(x)
+	case int:
+		bb.b = append(bb.b, tagInt)
+		bb.varint(int64(x))
+	case int32:
+		bb.b = append(bb.b, tagInt)
+		bb.varint(int64(x))
+	case int64:
+		bb.b = append(bb.b, tagInt)
+		bb.varint(x)
+	case uint:
+		bb.b = append(bb.b, tagUint)
+		bb.uvarint(uint64(x))
+	case uint32:
+		bb.b = append(bb.b, tagUint)
+		bb.uvarint(uint64(x))
+	case uint64:
+		bb.b = append(bb.b, tagUint)
+		bb.uvarint(x)
+	case float64:
+		bb.b = append(bb.b, tagFloat)
+		var tmp [8]byte
+		binary.LittleEndian.PutUint64(tmp[:], math.Float64bits(x))
+		bb.b = append(bb.b, tmp[:]...)
+	case bool:
+		bb.b = append(bb.b, tagBool)
+		if x {
+			bb.b = append(bb.b, 1)
+		} else {
+			bb.b = append(bb.b, 0)
+		}
+	case time.Time:
+		bb.b = append(bb.b, tagTime)
+		bb.varint(x.UnixNano())
+	case time.Duration:
+		bb.b = append(bb.b, tagDuration)
+		bb.varint(int64(x))
+	case Fields:
+		bb.b = append(bb.b, tagMap)
+		bb.fields(x)
+	case map[string]interface{}:
+		bb.b = append(bb.b, tagMap)
+		bb.fields(Fields(x))
+	case []interface{}:
+		bb.b = append(bb.b, tagList)
+		bb.uvarint(uint64(len(x)))
+		for _, v := range x {
+			bb.value(v)
+		}
+	case error:
+		bb.b = append(bb.b, tagString)
+		bb.string(x.Error())
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			data, _ = json.Marshal(map[string]string{"JsonErr": err.Error()})
+		}
+		bb.b = append(bb.b, tagJson)
+		bb.string(string(data))
+	}
+}
+
+func encodeBinaryBody(e Entry) []byte {
+	bb := &binaryBuffer{b: make([]byte, 0, 256)}
+	bb.varint(e.Timestamp().UnixNano())
+	bb.varint(int64(e.Level()))
+	bb.string(e.Hostname())
+	bb.uvarint(uint64(e.Pid()))
+	bb.string(e.Source())
+	bb.string(e.Message())
+	bb.string(maybeErrString(e.Err()))
+	stack := entryStackText(e)
+	bb.uvarint(uint64(len(stack)))
+	for _, frame := range stack {
+		bb.string(frame)
+	}
+	bb.fields(e.Fields())
+	return bb.b
+}
+
+// BinaryFmtEntry encodes an entry as a single binary record. Files should
+// begin with BinaryHeader, though readers tolerate its absence.
+func BinaryFmtEntry(e Entry) string {
+	body := encodeBinaryBody(e)
+	bb := &binaryBuffer{b: make([]byte, 0, len(body)+16)}
+	bb.b = append(bb.b, binaryMarker[:]...)
+	bb.uvarint(uint64(len(body)))
+	bb.b = append(bb.b, body...)
+	var crc [4]byte
+	binary.LittleEndian.PutUint32(crc[:], crc32.Checksum(body, crcTable))
+	bb.b = append(bb.b, crc[:]...)
+	return string(bb.b)
+}
+
+// BinaryHeader returns the header that starts a binary log file.
+func BinaryHeader() []byte {
+	return append([]byte(binaryMagic), binaryVersion)
+}
+
+// NewBinaryHandler writes the binary file header to wr and returns a
+// Handler writing records after it.
+func NewBinaryHandler(wr io.Writer) (Handler, error) {
+	if _, err := wr.Write(BinaryHeader()); err != nil {
+		return nil, err
+	}
+	return NewHandler(wr, BinaryFmtEntry), nil
+}
+
+var errCorruptRecord = stdErrors.New("corrupt binary log record")
+
+// binaryDecoder reads a record body. The first failure sticks: later
+// reads return zero values and err reports it.
+type binaryDecoder struct {
+	b   []byte
+	err error
+}
+
+func (bd *binaryDecoder) fail() {
+	bd.err = errCorruptRecord
+	bd.b = nil
+}
+
+func (bd *binaryDecoder) uvarint() uint64 {
+	if bd.err != nil {
+		return 0
+	}
+	x, n := binary.Uvarint(bd.b)
+	if n <= 0 {
+		bd.fail()
+		return 0
+	}
+	bd.b = bd.b[n:]
+	return x
+}
+
+func (bd *binaryDecoder) varint() int64 {
+	if bd.err != nil {
+		return 0
+	}
+	x, n := binary.Varint(bd.b)
+	if n <= 0 {
+		bd.fail()
+		return 0
+	}
+	bd.b = bd.b[n:]
+	return x
+}
+
+func (bd *binaryDecoder) bytes(n uint64) []byte {
+	if bd.err != nil {
+		return nil
+	}
+	if n > uint64(len(bd.b)) {
+		bd.fail()
+		return nil
+	}
+	b := bd.b[:n]
+	bd.b = bd.b[n:]
+	return b
+}
+
+func (bd *binaryDecoder) byte() byte {
+	if b := bd.bytes(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (bd *binaryDecoder) string() string {
+	return string(bd.bytes(bd.uvarint()))
+}
+
+// count reads a length prefix, which can't exceed the bytes left since
+// every element takes at least one.
+func (bd *binaryDecoder) count() uint64 {
+	n := bd.uvarint()
+	if n > uint64(len(bd.b)) {
+		bd.fail()
+		return 0
+	}
+	return n
+}
+
+func (bd *binaryDecoder) fields() Fields {
+	n := bd.count()
+	f := make(Fields, n)
+	for i := uint64(0); i < n && bd.err == nil; i++ {
+		k := bd.string()
+		f[k] = bd.value()
+	}
+	return f
+}
+
+func (bd *binaryDecoder) value() interface{} {
+	switch tag := bd.byte(); tag {
+	case tagNil:
+		return nil
+	case tagString:
+		return bd.string()
+	case tagInt:
+		return bd.varint()
+	case tagUint:
+		return bd.uvarint()
+	case tagFloat:
+		b := bd.bytes(8)
+		if b == nil {
+			return nil
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(b))
+	case tagBool:
+		return bd.byte() != 0
+	case tagTime:
+		return time.Unix(0, bd.varint()).UTC()
+	case tagDuration:
+		return time.Duration(bd.varint())
+	case tagMap:
+		return bd.fields()
+	case tagList:
+		l := make([]interface{}, bd.count())
+		for i := range l {
+			l[i] = bd.value()
+		}
+		return l
+	case tagJson:
+		var v interface{}
+		if data := bd.bytes(bd.uvarint()); bd.err == nil {
+			if err := json.Unmarshal(data, &v); err != nil {
+				bd.fail()
+			}
+		}
+		return v
+	}
+	bd.fail()
+	return nil
+}
+
+func decodeBinaryBody(body []byte) (Entry, error) {
+	bd := &binaryDecoder{b: body}
+	de := &decodedEntry{}
+	de.timestamp = time.Unix(0, bd.varint()).UTC()
+	de.level = Level(bd.varint())
+	de.hostname = bd.string()
+	de.pid = int(bd.uvarint())
+	de.source = bd.string()
+	de.message = bd.string()
+	if msg := bd.string(); msg != "" {
+		de.err = stdErrors.New(msg)
+	}
+	n := bd.count()
+	for i := uint64(0); i < n && bd.err == nil; i++ {
+		de.stack = append(de.stack, bd.string())
+	}
+	de.fields = bd.fields()
+	if bd.err != nil {
+		return nil, bd.err
+	}
+	return de, nil
+}
+
+// BinaryReader streams entries from a binary log. Corrupt or torn records
+// are skipped by scanning ahead for the next valid record.
+type BinaryReader struct {
+	rd      *bufio.Reader
+	header  bool
+	skipped int64
+}
+
+func NewBinaryReader(rd io.Reader) *BinaryReader {
+	return &BinaryReader{rd: bufio.NewReaderSize(rd, maxBinaryRecord+32)}
+}
+
+// Skipped returns the number of bytes discarded as corrupt so far.
+func (br *BinaryReader) Skipped() int64 {
+	return br.skipped
+}
+
+func (br *BinaryReader) discard(n int) {
+	n, _ = br.rd.Discard(n)
+	br.skipped += int64(n)
+}
+
+// Next returns the next entry, or io.EOF at the end of the stream. A
+// partial record at the end of the stream is treated as the end.
+func (br *BinaryReader) Next() (Entry, error) {
+	if !br.header {
+		br.header = true
+		if b, err := br.rd.Peek(len(binaryMagic) + 1); err == nil && string(b[:len(binaryMagic)]) == binaryMagic {
+			if version := b[len(binaryMagic)]; version != binaryVersion {
+				return nil, fmt.Errorf("unsupported binary log version: %d", version)
+			}
+			br.rd.Discard(len(b))
+		}
+	}
+
+	for {
+		b, err := br.rd.Peek(len(binaryMarker) + binary.MaxVarintLen64)
+		if len(b) < len(binaryMarker)+1 {
+			if err == nil || err == io.EOF {
+				err = io.EOF
+			}
+			return nil, err
+		}
+		if b[0] != binaryMarker[0] || b[1] != binaryMarker[1] {
+			br.discard(1)
+			continue
+		}
+		length, n := binary.Uvarint(b[len(binaryMarker):])
+		if n <= 0 || length > maxBinaryRecord {
+			br.discard(1)
+			continue
+		}
+		size := len(binaryMarker) + n + int(length) + 4
+		rec, err := br.rd.Peek(size)
+		if len(rec) < size {
+			if err == io.EOF {
+				// Torn write at the end; there may be a record hiding in the
+				// partial one, so keep scanning before giving up.
+				br.discard(1)
+				continue
+			}
+			return nil, err
+		}
+		body := rec[len(binaryMarker)+n : size-4]
+		if crc32.Checksum(body, crcTable) != binary.LittleEndian.Uint32(rec[size-4:]) {
+			br.discard(1)
+			continue
+		}
+		e, err := decodeBinaryBody(body)
+		if err != nil {
+			br.discard(1)
+			continue
+		}
+		br.rd.Discard(size)
+		return e, nil
+	}
+}
+
+// BinaryToJson converts a binary log to the format written by JsonFmtEntry.
+func BinaryToJson(rd io.Reader, wr io.Writer) error {
+	br := NewBinaryReader(rd)
+	for {
+		e, err := br.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(wr, JsonFmtEntry(e)); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package slog
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestBinaryRoundTrip(t *testing.T) {
+	buf := &bytes.Buffer{}
+	h, err := NewBinaryHandler(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &Config{}
+	slog := &slogger{h: h, cfg: cfg}
+	fields := Fields{
+		"s":   "string",
+		"i":   42,
+		"f":   1.5,
+		"b":   true,
+		"d":   time.Second,
+		"m":   map[string]interface{}{"nested": "yes"},
+		"any": struct{ X int }{7},
+	}
+	slog.WithFields(fields).Info("first")
+	slog.WithError(errors.New("with stack")).Error("second")
+	slog.Warn("third")
+	slog.Info("fourth")
+
+	data := buf.Bytes()
+	// Corrupt the third record and tear the fourth.
+	third := bytes.Index(data, []byte("third"))
+	data[third] ^= 0xff
+	data = data[:len(data)-3]
+
+	br := NewBinaryReader(bytes.NewReader(data))
+	var entries []Entry
+	for {
+		e, err := br.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, e)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 intact entries, found %d", len(entries))
+	}
+	if br.Skipped() == 0 {
+		t.Error("corrupt bytes not counted")
+	}
+
+	f := entries[0].Fields()
+	if f["s"] != "string" || f["i"] != int64(42) || f["f"] != 1.5 || f["b"] != true || f["d"] != time.Second {
+		t.Errorf("scalar fields did not round trip: %#v", f)
+	}
+	if f["m"].(Fields)["nested"] != "yes" || f["any"].(map[string]interface{})["X"] != float64(7) {
+		t.Errorf("composite fields did not round trip: %#v", f)
+	}
+	if !entries[0].Timestamp().Equal(fakeTime()) || entries[0].Level() != InfoLevel {
+		t.Errorf("header did not round trip: %v %v", entries[0].Timestamp(), entries[0].Level())
+	}
+
+	js := JsonFmtEntry(entries[1])
+	if !strings.Contains(js, "with stack") || !strings.Contains(js, "TestBinaryRoundTrip") {
+		t.Errorf("error and stack lost: %s", js)
+	}
+}
+
+func TestBinaryDecodeTruncated(t *testing.T) {
+	e := NewEntry(InfoLevel, "truncate me").SetFields(Fields{"l": []interface{}{1, "x"}, "m": Fields{"k": 1.5}})
+	body := encodeBinaryBody(e)
+	if _, err := decodeBinaryBody(body); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < len(body); i++ {
+		if _, err := decodeBinaryBody(body[:i]); err != errCorruptRecord {
+			t.Fatalf("body cut at %d: got %v", i, err)
+		}
+	}
+}
+
+func TestBinaryConfigHeader(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	fname := filepath.Join(tmpDir, "test.binlog")
+	cfg := &Config{Format: BinaryFormat, Outputs: []string{fname}, Rotate: RotateConfig{MaxBytes: 256}}
+	h, closer, err := NewConfigHandler(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	slog := &slogger{h: h, cfg: cfg}
+	for i := 0; i < 10; i++ {
+		slog.Infof("binary %d", i)
+	}
+	if err := closer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files, _ := filepath.Glob(fname + "*")
+	if len(files) < 2 {
+		t.Fatalf("expected rotation, found %v", files)
+	}
+	for _, f := range files {
+		data, err := ioutil.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.HasPrefix(data, BinaryHeader()) {
+			t.Errorf("%s lacks the binary header", f)
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	fh := NewFormatHandler(buf, cfg)
+	fh.WriteEntry(NewEntry(InfoLevel, "one"))
+	fh.WriteEntry(NewEntry(InfoLevel, "two"))
+	if !bytes.HasPrefix(buf.Bytes(), BinaryHeader()) || bytes.Count(buf.Bytes(), []byte(binaryMagic)) != 1 {
+		t.Errorf("stream should start with exactly one header: %q", buf.Bytes())
+	}
+}
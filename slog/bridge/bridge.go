@@ -0,0 +1,148 @@
+// Package bridge routes entries from logrus and apex/log through the
+// installed slog Handler, so libraries built on those loggers share the
+// application's output.
+package bridge
+
+import (
+	stdErrors "errors"
+	"fmt"
+	"runtime"
+	"strings"
+
+	apexLog "github.com/apex/log"
+	"github.com/msolo/go-bis/slog"
+	"github.com/sirupsen/logrus"
+)
+
+var skipPrefixes = []string{
+	"github.com/sirupsen/logrus.",
+	"github.com/apex/log.",
+	"github.com/msolo/go-bis/slog/bridge.",
+	"runtime.",
+}
+
+// callerSource returns the first frame outside the logging packages as
+// "file.go:line".
+func callerSource() string {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+	for {
+		frame, more := frames.Next()
+		skip := false
+		for _, prefix := range skipPrefixes {
+			if strings.HasPrefix(frame.Function, prefix) {
+				skip = true
+				break
+			}
+		}
+		if !skip {
+			return fileLine(frame.File, frame.Line)
+		}
+		if !more {
+			return "???:1"
+		}
+	}
+}
+
+func fileLine(file string, line int) string {
+	if slash := strings.LastIndex(file, "/"); slash >= 0 {
+		file = file[slash+1:]
+	}
+	return fmt.Sprintf("%s:%d", file, line)
+}
+
+// LogrusHook is a logrus.Hook that writes entries to the slog Handler.
+// Set the logger's Out to ioutil.Discard to avoid logging twice.
+type LogrusHook struct{}
+
+func NewLogrusHook() *LogrusHook {
+	return &LogrusHook{}
+}
+
+func (h *LogrusHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func logrusLevel(level logrus.Level) slog.Level {
+	switch level {
+	case logrus.PanicLevel, logrus.FatalLevel:
+		return slog.FatalLevel
+	case logrus.ErrorLevel:
+		return slog.ErrorLevel
+	case logrus.WarnLevel:
+		return slog.WarnLevel
+	case logrus.InfoLevel:
+		return slog.InfoLevel
+	}
+	return slog.DebugLevel
+}
+
+// Fire converts e into a slog entry. An error under logrus.ErrorKey
+// becomes the entry's error rather than a field.
+func (h *LogrusHook) Fire(e *logrus.Entry) error {
+	fields := make(slog.Fields, len(e.Data))
+	var err error
+	for k, v := range e.Data {
+		if k == logrus.ErrorKey {
+			if verr, ok := v.(error); ok {
+				err = verr
+				continue
+			}
+		}
+		fields[k] = v
+	}
+	src := ""
+	if e.HasCaller() {
+		src = fileLine(e.Caller.File, e.Caller.Line)
+	} else {
+		src = callerSource()
+	}
+	ent := slog.NewEntry(logrusLevel(e.Level), e.Message).
+		SetTimestamp(e.Time.UTC()).
+		SetSource(src).
+		SetFields(fields).
+		SetErr(err)
+	return slog.GetHandler().WriteEntry(ent)
+}
+
+// ApexHandler is an apex/log Handler that writes entries to the slog
+// Handler.
+type ApexHandler struct{}
+
+func NewApexHandler() *ApexHandler {
+	return &ApexHandler{}
+}
+
+func apexLevel(level apexLog.Level) slog.Level {
+	switch level {
+	case apexLog.FatalLevel:
+		return slog.FatalLevel
+	case apexLog.ErrorLevel:
+		return slog.ErrorLevel
+	case apexLog.WarnLevel:
+		return slog.WarnLevel
+	case apexLog.InfoLevel:
+		return slog.InfoLevel
+	}
+	return slog.DebugLevel
+}
+
+// HandleLog converts e into a slog entry. apex/log stores errors as the
+// "error" field string; it becomes the entry's error.
+func (h *ApexHandler) HandleLog(e *apexLog.Entry) error {
+	fields := make(slog.Fields, len(e.Fields))
+	var err error
+	for k, v := range e.Fields {
+		if s, ok := v.(string); ok && k == "error" {
+			err = stdErrors.New(s)
+			continue
+		}
+		fields[k] = v
+	}
+	ent := slog.NewEntry(apexLevel(e.Level), e.Message).
+		SetTimestamp(e.Timestamp.UTC()).
+		SetSource(callerSource()).
+		SetFields(fields).
+		SetErr(err)
+	return slog.GetHandler().WriteEntry(ent)
+}
@@ -0,0 +1,70 @@
+package bridge_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	apexLog "github.com/apex/log"
+	"github.com/msolo/go-bis/slog"
+	"github.com/msolo/go-bis/slog/bridge"
+	"github.com/sirupsen/logrus"
+)
+
+type entryLog []slog.Entry
+
+func (el *entryLog) WriteEntry(e slog.Entry) error {
+	*el = append(*el, e)
+	return nil
+}
+
+func capture() (el *entryLog, restore func()) {
+	el = &entryLog{}
+	saved := slog.GetHandler()
+	slog.SetHandler(el)
+	return el, func() { slog.SetHandler(saved) }
+}
+
+func checkEntry(t *testing.T, e slog.Entry, level slog.Level, msg string) {
+	t.Helper()
+	if e.Level() != level || e.Message() != msg {
+		t.Errorf("got %v %q, want %v %q", e.Level(), e.Message(), level, msg)
+	}
+	if !strings.HasPrefix(e.Source(), "bridge_test.go:") {
+		t.Errorf("bad source: %s", e.Source())
+	}
+	if e.Fields()["user"] != "bob" {
+		t.Errorf("missing field: %v", e.Fields())
+	}
+	if e.Err() == nil || e.Err().Error() != "disk full" {
+		t.Errorf("bad error: %v", e.Err())
+	}
+	if _, ok := e.Fields()["error"]; ok {
+		t.Errorf("error left in fields: %v", e.Fields())
+	}
+}
+
+func TestLogrusHook(t *testing.T) {
+	el, restore := capture()
+	defer restore()
+	lg := logrus.New()
+	lg.Out = ioutil.Discard
+	lg.AddHook(bridge.NewLogrusHook())
+	lg.WithField("user", "bob").WithError(errors.New("disk full")).Warn("write failed")
+	if len(*el) != 1 {
+		t.Fatalf("got %d entries", len(*el))
+	}
+	checkEntry(t, (*el)[0], slog.WarnLevel, "write failed")
+}
+
+func TestApexHandler(t *testing.T) {
+	el, restore := capture()
+	defer restore()
+	lg := &apexLog.Logger{Handler: bridge.NewApexHandler(), Level: apexLog.DebugLevel}
+	lg.WithField("user", "bob").WithError(errors.New("disk full")).Error("write failed")
+	if len(*el) != 1 {
+		t.Fatalf("got %d entries", len(*el))
+	}
+	checkEntry(t, (*el)[0], slog.ErrorLevel, "write failed")
+}
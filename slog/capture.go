@@ -0,0 +1,183 @@
+package slog
+
+import (
+	"bufio"
+	stdErrors "errors"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// stdRedirect holds the original descriptors of the standard streams while
+// they are captured.
+type stdRedirect struct {
+	stdout, stderr         *os.File // the streams being captured
+	origStdout, origStderr *os.File // duplicates of the original descriptors
+}
+
+var (
+	captureMu     sync.Mutex
+	activeCapture atomic.Value // *stdRedirect
+)
+
+func init() {
+	activeCapture.Store((*stdRedirect)(nil))
+}
+
+// stdioWriter returns where writes to wr should go, sending slog's own
+// output for a captured stream to the original descriptor rather than
+// back into the capture.
+func stdioWriter(wr io.Writer) io.Writer {
+	rd := activeCapture.Load().(*stdRedirect)
+	if rd == nil {
+		return wr
+	}
+	if f, ok := wr.(*os.File); ok {
+		switch f {
+		case rd.stdout:
+			return rd.origStdout
+		case rd.stderr:
+			return rd.origStderr
+		}
+	}
+	return wr
+}
+
+type capturedStream struct {
+	fd    int
+	name  string
+	orig  *os.File
+	rd    *os.File
+	done  chan struct{}
+	level Level
+}
+
+// redirect points the stream's descriptor at a new pipe and starts
+// logging what is written to it.
+func (cs *capturedStream) redirect() error {
+	rd, wr, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	err = dup2(int(wr.Fd()), cs.fd)
+	wr.Close()
+	if err != nil {
+		rd.Close()
+		return err
+	}
+	cs.rd = rd
+	go cs.copyLines()
+	return nil
+}
+
+// copyLines logs each line read from the pipe until every writer is gone.
+func (cs *capturedStream) copyLines() {
+	defer close(cs.done)
+	lg := std.WithSource("???:1").WithFields(Fields{"stream": cs.name}).(*entrySlogger)
+	br := bufio.NewReader(cs.rd)
+	for {
+		line, err := br.ReadString('\n')
+		if line = strings.TrimSuffix(line, "\n"); line != "" {
+			lg.log(cs.level, line)
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// captureDrain bounds how long restore keeps reading after putting a
+// stream back, for output still in the pipe.
+const captureDrain = 100 * time.Millisecond
+
+func (cs *capturedStream) restore() error {
+	err := dup2(int(cs.orig.Fd()), cs.fd)
+	if err == nil {
+		// The reader sees EOF once nothing refers to the pipe, but child
+		// processes may hold it open indefinitely; read what is there
+		// and stop.
+		if cs.rd.SetReadDeadline(time.Now().Add(captureDrain)) == nil {
+			<-cs.done
+		}
+	}
+	cs.rd.Close()
+	return err
+}
+
+// CaptureStdStreams redirects the process's stdout and stderr descriptors
+// into pipes and logs each line written to them at level, with a "stream"
+// field of "stdout" or "stderr". This catches output from code that
+// bypasses slog, including C libraries and fmt.Fprintln(os.Stderr, ...).
+//
+// Handlers writing to os.Stdout or os.Stderr keep writing to the original
+// descriptors; ones that opened /dev/stderr by name would loop and must be
+// avoided. restore puts the descriptors back and logs any partial line.
+//
+// Child processes started during the capture inherit the pipes. restore
+// doesn't wait for them to exit: it reads for at most a short while after
+// putting the descriptors back, then closes the pipes, so later writes
+// by such a child fail with EPIPE.
+func CaptureStdStreams(level Level) (restore func() error, err error) {
+	captureMu.Lock()
+	defer captureMu.Unlock()
+	if activeCapture.Load().(*stdRedirect) != nil {
+		return nil, stdErrors.New("std streams already captured")
+	}
+
+	streams := []*capturedStream{
+		{fd: 1, name: "stdout", level: level, done: make(chan struct{})},
+		{fd: 2, name: "stderr", level: level, done: make(chan struct{})},
+	}
+	var redirected []*capturedStream
+	undo := func() error {
+		var firstErr error
+		for _, cs := range redirected {
+			if err := cs.restore(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+		activeCapture.Store((*stdRedirect)(nil))
+		for _, cs := range streams {
+			if cs.orig != nil {
+				cs.orig.Close()
+			}
+		}
+		return firstErr
+	}
+
+	for _, cs := range streams {
+		origFd, err := dup(cs.fd)
+		if err != nil {
+			undo()
+			return nil, err
+		}
+		cs.orig = os.NewFile(uintptr(origFd), cs.name)
+	}
+	// Divert slog's own output before the descriptors change under it.
+	rd := &stdRedirect{
+		stdout:     os.Stdout,
+		stderr:     os.Stderr,
+		origStdout: streams[0].orig,
+		origStderr: streams[1].orig,
+	}
+	activeCapture.Store(rd)
+	for _, cs := range streams {
+		if err := cs.redirect(); err != nil {
+			undo()
+			return nil, err
+		}
+		redirected = append(redirected, cs)
+	}
+
+	return func() error {
+		captureMu.Lock()
+		defer captureMu.Unlock()
+		if activeCapture.Load().(*stdRedirect) != rd {
+			return nil
+		}
+		return undo()
+	}, nil
+}
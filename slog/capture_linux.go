@@ -0,0 +1,13 @@
+package slog
+
+import "syscall"
+
+func dup(fd int) (int, error) {
+	return syscall.Dup(fd)
+}
+
+// dup2 makes newfd a copy of oldfd. Some Linux architectures only have
+// dup3.
+func dup2(oldfd, newfd int) error {
+	return syscall.Dup3(oldfd, newfd, 0)
+}
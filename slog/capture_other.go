@@ -0,0 +1,16 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+// +build !linux,!darwin,!dragonfly,!freebsd,!netbsd,!openbsd
+
+package slog
+
+import "errors"
+
+var errNoCapture = errors.New("capturing std streams is not supported on this platform")
+
+func dup(fd int) (int, error) {
+	return -1, errNoCapture
+}
+
+func dup2(oldfd, newfd int) error {
+	return errNoCapture
+}
@@ -0,0 +1,103 @@
+package slog
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"testing"
+	"time"
+)
+
+// entryList collects entries from concurrent writers.
+type entryList struct {
+	mu      sync.Mutex
+	entries []Entry
+}
+
+func (el *entryList) WriteEntry(e Entry) error {
+	el.mu.Lock()
+	defer el.mu.Unlock()
+	el.entries = append(el.entries, e)
+	return nil
+}
+
+func (el *entryList) Entries() []Entry {
+	el.mu.Lock()
+	defer el.mu.Unlock()
+	return append([]Entry(nil), el.entries...)
+}
+
+func TestCaptureStdStreams(t *testing.T) {
+	el := &entryList{}
+	saved := GetHandler()
+	SetHandler(el)
+	defer SetHandler(saved)
+
+	restore, err := CaptureStdStreams(WarnLevel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CaptureStdStreams(WarnLevel); err == nil {
+		t.Error("nested capture allowed")
+	}
+	if stdioWriter(os.Stderr) == os.Stderr {
+		t.Error("slog output to stderr would be captured")
+	}
+	fmt.Fprintln(os.Stderr, "to stderr")
+	fmt.Fprint(os.Stdout, "partial")
+	if err := restore(); err != nil {
+		t.Fatal(err)
+	}
+	if stdioWriter(os.Stderr) != os.Stderr {
+		t.Error("stderr not restored")
+	}
+
+	got := map[string]string{}
+	for _, e := range el.Entries() {
+		if e.Level() != WarnLevel {
+			t.Errorf("bad level: %v", e.Level())
+		}
+		got[e.Fields()["stream"].(string)] = e.Message()
+	}
+	if got["stderr"] != "to stderr" || got["stdout"] != "partial" {
+		t.Errorf("bad capture: %v", got)
+	}
+}
+
+func TestCaptureRestoreWithChild(t *testing.T) {
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("no sleep command")
+	}
+	el := &entryList{}
+	saved := GetHandler()
+	SetHandler(el)
+	defer SetHandler(saved)
+
+	restore, err := CaptureStdStreams(WarnLevel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The child holds the write end of the stdout pipe open.
+	cmd := exec.Command("sleep", "10")
+	cmd.Stdout = os.Stdout
+	if err := cmd.Start(); err != nil {
+		restore()
+		t.Fatal(err)
+	}
+	defer cmd.Wait()
+	defer cmd.Process.Kill()
+	fmt.Fprintln(os.Stdout, "before restore")
+
+	start := time.Now()
+	if err := restore(); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("restore waited %v for the child", d)
+	}
+	entries := el.Entries()
+	if len(entries) != 1 || entries[0].Message() != "before restore" {
+		t.Errorf("unexpected entries: %v", entries)
+	}
+}
@@ -0,0 +1,15 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+// +build darwin dragonfly freebsd netbsd openbsd
+
+package slog
+
+import "syscall"
+
+func dup(fd int) (int, error) {
+	return syscall.Dup(fd)
+}
+
+// dup2 makes newfd a copy of oldfd.
+func dup2(oldfd, newfd int) error {
+	return syscall.Dup2(oldfd, newfd)
+}
@@ -0,0 +1,191 @@
+// Command slog inspects logs written by github.com/msolo/go-bis/slog.
+//
+//	slog query -dir /var/log/app -from 15m -level warn -field req=42
+//	slog cat -fmt json app.binlog
+//	slog audit verify -key-file /etc/app/audit.key /var/log/app/audit.log
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/msolo/go-bis/slog"
+)
+
+type commandFunc func(args []string) error
+
+var commands = map[string]commandFunc{
+	"query": queryCmd,
+	"cat":   catCmd,
+	"audit": auditCmd,
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: slog <command> [flags]\n\ncommands:\n")
+	for name := range commands {
+		fmt.Fprintf(os.Stderr, "  %s\n", name)
+	}
+	os.Exit(2)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		usage()
+	}
+	if err := cmd(os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "slog %s: %v\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
+
+// fieldFlags collects repeated -field key=value flags.
+type fieldFlags map[string]string
+
+func (ff fieldFlags) String() string {
+	pairs := make([]string, 0, len(ff))
+	for k, v := range ff {
+		pairs = append(pairs, k+"="+v)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (ff fieldFlags) Set(val string) error {
+	kv := strings.SplitN(val, "=", 2)
+	if len(kv) != 2 {
+		return fmt.Errorf("expected key=value: %s", val)
+	}
+	ff[kv[0]] = kv[1]
+	return nil
+}
+
+// parseTime accepts RFC 3339 times or a duration before now, like "15m".
+func parseTime(val string) (time.Time, error) {
+	if val == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(strings.TrimPrefix(val, "-")); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	return time.Parse(time.RFC3339, val)
+}
+
+func queryCmd(args []string) error {
+	fs := flag.NewFlagSet("query", flag.ExitOnError)
+	dir := fs.String("dir", "", "store directory")
+	from := fs.String("from", "", "start time, RFC 3339 or a duration ago")
+	to := fs.String("to", "", "end time, RFC 3339 or a duration ago")
+	source := fs.String("source", "", "only entries from this source file")
+	level := slog.DebugLevel
+	fs.Var(&level, "level", "only entries at or above this level")
+	format := slog.AutoFormat
+	fs.Var(&format, "fmt", "output format: auto, console, glog, json or binary")
+	fields := fieldFlags{}
+	fs.Var(fields, "field", "only entries with field key=value; may be repeated")
+	fs.Parse(args)
+	if *dir == "" {
+		return fmt.Errorf("-dir is required")
+	}
+
+	q := slog.StoreQuery{MinLevel: level, Source: *source, FieldEq: fields}
+	var err error
+	if q.From, err = parseTime(*from); err != nil {
+		return err
+	}
+	if q.To, err = parseTime(*to); err != nil {
+		return err
+	}
+
+	it, err := slog.SearchDir(*dir, q)
+	if err != nil {
+		return err
+	}
+	fmtEntry := format.FmtEntry(os.Stdout)
+	for it.Next() {
+		if _, err := os.Stdout.WriteString(fmtEntry(it.Entry())); err != nil {
+			it.Close()
+			return err
+		}
+	}
+	return it.Close()
+}
+
+// catCmd decodes binary logs, from stdin if no files are named.
+func catCmd(args []string) error {
+	fs := flag.NewFlagSet("cat", flag.ExitOnError)
+	format := slog.JsonFormat
+	fs.Var(&format, "fmt", "output format: auto, console, glog, json or binary")
+	fs.Parse(args)
+
+	fmtEntry := format.FmtEntry(os.Stdout)
+	cat := func(rd io.Reader) error {
+		br := slog.NewBinaryReader(rd)
+		for {
+			e, err := br.Next()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return err
+			}
+			if _, err := os.Stdout.WriteString(fmtEntry(e)); err != nil {
+				return err
+			}
+		}
+		if n := br.Skipped(); n > 0 {
+			fmt.Fprintf(os.Stderr, "slog cat: skipped %d corrupt bytes\n", n)
+		}
+		return nil
+	}
+
+	if fs.NArg() == 0 {
+		return cat(os.Stdin)
+	}
+	for _, fname := range fs.Args() {
+		f, err := os.Open(fname)
+		if err != nil {
+			return err
+		}
+		err = cat(f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// auditCmd checks the hash chain of audit logs. A nonzero exit status
+// means a log was altered, truncated or signed with a different key.
+func auditCmd(args []string) error {
+	if len(args) == 0 || args[0] != "verify" {
+		return fmt.Errorf("usage: slog audit verify -key-file <file> <log>...")
+	}
+	fs := flag.NewFlagSet("audit verify", flag.ExitOnError)
+	keyFile := fs.String("key-file", "", "file holding the HMAC key")
+	fs.Parse(args[1:])
+	if *keyFile == "" {
+		return fmt.Errorf("-key-file is required")
+	}
+	key, err := ioutil.ReadFile(*keyFile)
+	if err != nil {
+		return err
+	}
+	key = []byte(strings.TrimSpace(string(key)))
+
+	for _, fname := range fs.Args() {
+		n, err := slog.VerifyAudit(fname, key)
+		if err != nil {
+			return fmt.Errorf("%s: %d records verified before: %v", fname, n, err)
+		}
+		fmt.Printf("%s: %d records verified\n", fname, n)
+	}
+	return nil
+}
@@ -0,0 +1,275 @@
+package slog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// EnvPrefix is prepended to the variable names read by LoadEnv.
+var EnvPrefix = "SLOG_"
+
+// fileConfig is the on-disk form of a Config. Levels, formats and
+// durations are plain strings since that is what people write by hand.
+type fileConfig struct {
+	Level      string   `json:"level" yaml:"level"`
+	Levels     string   `json:"levels" yaml:"levels"`
+	Format     string   `json:"format" yaml:"format"`
+	JsonSchema string   `json:"json_schema" yaml:"json_schema"`
+	Outputs    []string `json:"outputs" yaml:"outputs"`
+	AsyncQueue int      `json:"async_queue" yaml:"async_queue"`
+	Rotate     struct {
+		MaxBytes   int64  `json:"max_bytes" yaml:"max_bytes"`
+		MaxAge     string `json:"max_age" yaml:"max_age"`
+		MaxBackups int    `json:"max_backups" yaml:"max_backups"`
+	} `json:"rotate" yaml:"rotate"`
+	Sync         string `json:"sync" yaml:"sync"`
+	SyncInterval string `json:"sync_interval" yaml:"sync_interval"`
+}
+
+func (fc *fileConfig) apply(cfg *Config) (err error) {
+	if fc.Level != "" {
+		if err := cfg.Level.Set(fc.Level); err != nil {
+			return err
+		}
+	}
+	if fc.Levels != "" {
+		if err := cfg.Levels.Set(fc.Levels); err != nil {
+			return err
+		}
+	}
+	if fc.Format != "" {
+		if err := cfg.Format.Set(fc.Format); err != nil {
+			return err
+		}
+	}
+	if fc.JsonSchema != "" {
+		if err := cfg.JsonSchema.Set(fc.JsonSchema); err != nil {
+			return err
+		}
+	}
+	cfg.Outputs = fc.Outputs
+	cfg.AsyncQueue = fc.AsyncQueue
+	cfg.Rotate.MaxBytes = fc.Rotate.MaxBytes
+	cfg.Rotate.MaxBackups = fc.Rotate.MaxBackups
+	if fc.Rotate.MaxAge != "" {
+		if cfg.Rotate.MaxAge, err = time.ParseDuration(fc.Rotate.MaxAge); err != nil {
+			return err
+		}
+	}
+	if fc.Sync != "" {
+		if err := cfg.Sync.Set(fc.Sync); err != nil {
+			return err
+		}
+	}
+	if fc.SyncInterval != "" {
+		if cfg.SyncInterval, err = time.ParseDuration(fc.SyncInterval); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadConfig reads a Config from a JSON or YAML file, chosen by extension.
+func LoadConfig(fname string) (*Config, error) {
+	data, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+	return parseConfig(fname, data)
+}
+
+func parseConfig(fname string, data []byte) (cfg *Config, err error) {
+	fc := &fileConfig{}
+	switch ext := strings.ToLower(filepath.Ext(fname)); ext {
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(fc)
+	case ".yaml", ".yml":
+		err = yaml.UnmarshalStrict(data, fc)
+	default:
+		return nil, fmt.Errorf("unknown log config extension: %s", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid log config %s: %v", fname, err)
+	}
+	cfg = &Config{}
+	if err := fc.apply(cfg); err != nil {
+		return nil, fmt.Errorf("invalid log config %s: %v", fname, err)
+	}
+	return cfg, nil
+}
+
+// LoadEnv overrides cfg with whichever of SLOG_LEVEL, SLOG_LEVELS,
+// SLOG_FORMAT, SLOG_JSON_SCHEMA, SLOG_OUTPUTS (comma separated),
+// SLOG_ASYNC_QUEUE, SLOG_ROTATE_MAX_BYTES, SLOG_ROTATE_MAX_AGE,
+// SLOG_ROTATE_MAX_BACKUPS, SLOG_SYNC and SLOG_SYNC_INTERVAL are set.
+func LoadEnv(cfg *Config) (err error) {
+	lookup := func(name string) (string, bool) {
+		return os.LookupEnv(EnvPrefix + name)
+	}
+	if val, ok := lookup("LEVEL"); ok {
+		if err := cfg.Level.Set(val); err != nil {
+			return err
+		}
+	}
+	if val, ok := lookup("LEVELS"); ok {
+		if err := cfg.Levels.Set(val); err != nil {
+			return err
+		}
+	}
+	if val, ok := lookup("FORMAT"); ok {
+		if err := cfg.Format.Set(val); err != nil {
+			return err
+		}
+	}
+	if val, ok := lookup("JSON_SCHEMA"); ok {
+		if err := cfg.JsonSchema.Set(val); err != nil {
+			return err
+		}
+	}
+	if val, ok := lookup("OUTPUTS"); ok {
+		cfg.Outputs = strings.Split(val, ",")
+		for i, name := range cfg.Outputs {
+			cfg.Outputs[i] = strings.TrimSpace(name)
+		}
+	}
+	if val, ok := lookup("ASYNC_QUEUE"); ok {
+		if cfg.AsyncQueue, err = strconv.Atoi(val); err != nil {
+			return fmt.Errorf("invalid %sASYNC_QUEUE: %v", EnvPrefix, err)
+		}
+	}
+	if val, ok := lookup("ROTATE_MAX_BYTES"); ok {
+		if cfg.Rotate.MaxBytes, err = strconv.ParseInt(val, 10, 64); err != nil {
+			return fmt.Errorf("invalid %sROTATE_MAX_BYTES: %v", EnvPrefix, err)
+		}
+	}
+	if val, ok := lookup("ROTATE_MAX_AGE"); ok {
+		if cfg.Rotate.MaxAge, err = time.ParseDuration(val); err != nil {
+			return fmt.Errorf("invalid %sROTATE_MAX_AGE: %v", EnvPrefix, err)
+		}
+	}
+	if val, ok := lookup("ROTATE_MAX_BACKUPS"); ok {
+		if cfg.Rotate.MaxBackups, err = strconv.Atoi(val); err != nil {
+			return fmt.Errorf("invalid %sROTATE_MAX_BACKUPS: %v", EnvPrefix, err)
+		}
+	}
+	if val, ok := lookup("SYNC"); ok {
+		if err := cfg.Sync.Set(val); err != nil {
+			return err
+		}
+	}
+	if val, ok := lookup("SYNC_INTERVAL"); ok {
+		if cfg.SyncInterval, err = time.ParseDuration(val); err != nil {
+			return fmt.Errorf("invalid %sSYNC_INTERVAL: %v", EnvPrefix, err)
+		}
+	}
+	return nil
+}
+
+// multiCloser closes everything in order and reports the first error.
+type multiCloser []io.Closer
+
+func (mc multiCloser) Close() error {
+	var err error
+	for _, c := range mc {
+		if closeErr := c.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// newOutputHandler returns a handler for a file path, "stdout", "stderr"
+// or "stdstreams", and a Closer if it opened a file.
+func newOutputHandler(name string, cfg *Config) (Handler, io.Closer, error) {
+	switch name {
+	case "", "stderr", "/dev/stderr":
+		return NewFormatHandler(os.Stderr, cfg), nil, nil
+	case "stdout", "/dev/stdout":
+		return NewFormatHandler(os.Stdout, cfg), nil, nil
+	case "stdstreams":
+		isTerm := isTerminal(os.Stderr)
+		return NewStdStreamsHandler(func(e Entry) string {
+			return cfg.fmtEntry(isTerm)(e)
+		}), nil, nil
+	}
+	fmtEntry := func(e Entry) string {
+		return cfg.fmtEntry(false)(e)
+	}
+	rotate := cfg.Rotate
+	if cfg.Format == BinaryFormat && rotate.Header == nil {
+		rotate.Header = func() ([]byte, error) {
+			return BinaryHeader(), nil
+		}
+	}
+	fh, err := OpenFileHandler(name, fmtEntry, FileConfig{
+		Sync:         cfg.Sync,
+		SyncInterval: cfg.SyncInterval,
+		Rotate:       rotate,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return fh, fh, nil
+}
+
+// NewConfigHandler builds the handler tree described by cfg without
+// installing it. The returned Closer flushes and closes any outputs it
+// opened.
+func NewConfigHandler(cfg *Config) (Handler, io.Closer, error) {
+	outputs := cfg.Outputs
+	if len(outputs) == 0 {
+		outputs = []string{cfg.Fname}
+	}
+
+	var closers multiCloser
+	handlers := make([]Handler, 0, len(outputs))
+	for _, name := range outputs {
+		h, c, err := newOutputHandler(name, cfg)
+		if err != nil {
+			closers.Close()
+			return nil, nil, err
+		}
+		if c != nil {
+			closers = append(closers, c)
+		}
+		handlers = append(handlers, h)
+	}
+
+	h := handlers[0]
+	if len(handlers) > 1 {
+		h = NewMultiHandler(handlers...)
+	}
+	if cfg.AsyncQueue > 0 {
+		ah := NewAsyncHandler(h, cfg.AsyncQueue)
+		// Drain the queue before closing the files underneath it.
+		closers = append(multiCloser{ah}, closers...)
+		h = ah
+	}
+	if cfg.Metrics != nil {
+		h = NewMetricsHandler(h, cfg.Metrics)
+	}
+	return NewLevelHandler(h, cfg), closers, nil
+}
+
+// Setup builds the handler tree described by cfg and installs it as the
+// default. Close the result before exit to flush pending entries.
+func Setup(cfg *Config) (io.Closer, error) {
+	h, closer, err := NewConfigHandler(cfg)
+	if err != nil {
+		return nil, err
+	}
+	SetHandler(h)
+	return closer, nil
+}
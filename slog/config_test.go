@@ -0,0 +1,138 @@
+package slog
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	fname := filepath.Join(tmpDir, "log.yaml")
+	data := "level: warn\nformat: json\noutputs: [stderr]\nrotate:\n  max_age: 1h\n"
+	if err := ioutil.WriteFile(fname, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfig(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Level != WarnLevel || cfg.Format != JsonFormat || cfg.Rotate.MaxAge != time.Hour {
+		t.Fatalf("unexpected config: %+v", cfg)
+	}
+
+	os.Setenv("SLOG_LEVEL", "error")
+	defer os.Unsetenv("SLOG_LEVEL")
+	os.Setenv("SLOG_OUTPUTS", "stderr, /tmp/app.log")
+	defer os.Unsetenv("SLOG_OUTPUTS")
+	if err := LoadEnv(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Level != ErrorLevel {
+		t.Fatalf("env did not override level: %v", cfg.Level)
+	}
+	if len(cfg.Outputs) != 2 || cfg.Outputs[1] != "/tmp/app.log" {
+		t.Fatalf("outputs not split and trimmed: %q", cfg.Outputs)
+	}
+
+	if err := ioutil.WriteFile(fname, []byte("levle: warn\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig(fname); err == nil {
+		t.Fatal("expected error for unknown key")
+	}
+}
+
+func TestConfigHandlerRotation(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	fname := filepath.Join(tmpDir, "test.log")
+	cfg := &Config{
+		Format:     JsonFormat,
+		Outputs:    []string{fname},
+		AsyncQueue: 16,
+		Rotate:     RotateConfig{MaxBytes: 256, MaxBackups: 2},
+	}
+	h, closer, err := NewConfigHandler(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	slog := &slogger{h: h, cfg: cfg}
+	for i := 0; i < 10; i++ {
+		slog.Infof("rotate me %d", i)
+	}
+	if err := closer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	backups, _ := filepath.Glob(fname + ".*")
+	if len(backups) != 2 {
+		t.Fatalf("expected 2 backups, found %v", backups)
+	}
+	data, err := ioutil.ReadFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "rotate me 9") {
+		t.Fatalf("last entry missing from active file: %s", data)
+	}
+}
+
+func TestRotationFailure(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	fname := filepath.Join(tmpDir, "test.log")
+	headers := 0
+	rf, err := OpenRotatingFile(fname, 0644, RotateConfig{
+		MaxBytes: 16,
+		Header: func() ([]byte, error) {
+			headers++
+			if headers > 1 {
+				return nil, errors.New("no header for you")
+			}
+			return []byte("header\n"), nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rf.Rotate(); err == nil {
+		t.Fatal("expected rotation error")
+	}
+	for _, line := range []string{"first line\n", "second line\n"} {
+		if _, err := rf.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := rf.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "header\nfirst line\nsecond line\n" {
+		t.Fatalf("writes did not continue to the old file: %q", data)
+	}
+	if backups, _ := filepath.Glob(fname + ".*"); len(backups) != 0 {
+		t.Fatalf("old file not restored: %v", backups)
+	}
+}
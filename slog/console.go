@@ -0,0 +1,114 @@
+package slog
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Entries are stamped relative to process start in console output.
+var startTime = time.Now()
+
+// Pad sources so messages line up for the common case.
+const consoleSourceWidth = 24
+
+const (
+	ansiReset = "\x1b[0m"
+	ansiDim   = "\x1b[2m"
+)
+
+// levelColor picks a color by the built-in level at or below l.
+func levelColor(l Level) string {
+	switch {
+	case l < InfoLevel:
+		return "\x1b[90m" // debug: gray
+	case l < WarnLevel:
+		return "\x1b[36m" // info: cyan
+	case l < ErrorLevel:
+		return "\x1b[33m" // warn: yellow
+	case l < FatalLevel:
+		return "\x1b[31m" // error: red
+	}
+	return "\x1b[1;35m" // fatal: bold magenta
+}
+
+// ConsoleFmtEntry formats an entry for a human at a terminal. Fields are
+// printed as indented key=value lines and stack frames one per line.
+func ConsoleFmtEntry(e Entry) string {
+	return consoleFmtEntry(e, true)
+}
+
+// PlainConsoleFmtEntry is ConsoleFmtEntry without ANSI colors, for the
+// console format written to files and pipes.
+func PlainConsoleFmtEntry(e Entry) string {
+	return consoleFmtEntry(e, false)
+}
+
+func consoleFmtEntry(e Entry, color bool) string {
+	b := &strings.Builder{}
+	level := e.Level()
+	elapsed := e.Timestamp().Sub(startTime).Seconds()
+	ansi := func(code string) string {
+		if color {
+			return code
+		}
+		return ""
+	}
+	levelCode, reset, dim := ansi(levelColor(level)), ansi(ansiReset), ansi(ansiDim)
+
+	fmt.Fprintf(b, "%s%c%s %s%+10.3fs %-*s%s %s\n",
+		levelCode, levelChar(level), reset,
+		dim, elapsed, consoleSourceWidth, e.Source(), reset,
+		e.Message())
+
+	fields := e.Fields()
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(b, "    %s%s%s=%s\n", levelCode, k, reset, consoleValue(fields[k]))
+	}
+
+	if err := e.Err(); err != nil {
+		fmt.Fprintf(b, "    %serr%s=%s\n", levelCode, reset, consoleValue(err.Error()))
+	}
+	if stx, ok := e.(stackTexter); ok {
+		for _, frame := range stx.Stack() {
+			fmt.Fprintf(b, "        %s\n", frame)
+		}
+	}
+	for _, frame := range e.StackTrace() {
+		// %+s renders as "func\n\tpath"; indent the path under the func.
+		fn := strings.SplitN(fmt.Sprintf("%+s", frame), "\n\t", 2)
+		if len(fn) == 2 {
+			fmt.Fprintf(b, "        %s\n          %s%s:%d%s\n", fn[0], dim, fn[1], frame, reset)
+		} else {
+			fmt.Fprintf(b, "        %s:%d\n", fn[0], frame)
+		}
+	}
+	return b.String()
+}
+
+func consoleValue(v interface{}) string {
+	switch x := v.(type) {
+	case string:
+		if x == "" || strings.ContainsAny(x, " \t\r\n\"=") {
+			return strconv.Quote(x)
+		}
+		return x
+	case fmt.Stringer:
+		return consoleValue(x.String())
+	case error:
+		return consoleValue(x.Error())
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(data)
+}
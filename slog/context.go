@@ -0,0 +1,21 @@
+package slog
+
+import "context"
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx that carries lg, typically a Slogger
+// with request fields attached.
+func NewContext(ctx context.Context, lg Slogger) context.Context {
+	return context.WithValue(ctx, contextKey{}, lg)
+}
+
+// FromContext returns the Slogger carried by ctx, or the default logger.
+func FromContext(ctx context.Context) Slogger {
+	if ctx != nil {
+		if lg, ok := ctx.Value(contextKey{}).(Slogger); ok {
+			return lg
+		}
+	}
+	return std
+}
@@ -0,0 +1,218 @@
+package slog
+
+import (
+	"encoding/json"
+	stdErrors "errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// decodedEntry is an Entry read back from storage. Stack traces survive
+// only as text, so StackTrace is always nil; see Stack.
+type decodedEntry struct {
+	level     Level
+	timestamp time.Time
+	hostname  string
+	pid       int
+	source    string
+	message   string
+	fields    Fields
+	err       error
+	stack     []string
+}
+
+func (de *decodedEntry) Timestamp() time.Time {
+	return de.timestamp
+}
+
+func (de *decodedEntry) Source() string {
+	return de.source
+}
+
+func (de *decodedEntry) Message() string {
+	return de.message
+}
+
+func (de *decodedEntry) Fields() Fields {
+	return de.fields
+}
+
+func (de *decodedEntry) Err() error {
+	return de.err
+}
+
+func (de *decodedEntry) StackTrace() errors.StackTrace {
+	return nil
+}
+
+func (de *decodedEntry) Pid() int {
+	return de.pid
+}
+
+func (de *decodedEntry) Hostname() string {
+	return de.hostname
+}
+
+func (de *decodedEntry) Level() Level {
+	return de.level
+}
+
+// Stack returns the recorded stack frames, one per string.
+func (de *decodedEntry) Stack() []string {
+	return de.stack
+}
+
+func (de *decodedEntry) MarshalJSON() ([]byte, error) {
+	return marshalEntryJSON(de)
+}
+
+// ParseJsonEntry decodes one line written by JsonFmtEntry. Levels may be
+// names or, as older versions wrote them, numbers.
+func ParseJsonEntry(data []byte) (Entry, error) {
+	st := struct {
+		Level      json.RawMessage
+		Timestamp  time.Time
+		Hostname   string
+		Pid        int
+		Source     string
+		Message    string
+		Fields     Fields
+		Err        string
+		StackTrace []string
+	}{}
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, err
+	}
+	level, err := parseJsonLevel(st.Level)
+	if err != nil {
+		return nil, err
+	}
+	de := &decodedEntry{
+		level:     level,
+		timestamp: st.Timestamp,
+		hostname:  st.Hostname,
+		pid:       st.Pid,
+		source:    st.Source,
+		message:   st.Message,
+		fields:    st.Fields,
+		stack:     st.StackTrace,
+	}
+	if st.Err != "" {
+		de.err = stdErrors.New(st.Err)
+	}
+	return de, nil
+}
+
+func parseJsonLevel(data json.RawMessage) (level Level, err error) {
+	if len(data) == 0 {
+		return DebugLevel, nil
+	}
+	if data[0] == '"' {
+		err = json.Unmarshal(data, &level)
+		return level, err
+	}
+	n, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil {
+		return InvalidLevel, fmt.Errorf("invalid log level: %s", data)
+	}
+	if level = legacyLevel(n); level == InvalidLevel {
+		return InvalidLevel, fmt.Errorf("invalid log level: %s", data)
+	}
+	return level, nil
+}
+
+// glogUnescape reverses glogEscaper. Lines written before messages were
+// escaped only lose a backslash before n, r, | or another backslash.
+func glogUnescape(s string) string {
+	if strings.IndexByte(s, '\\') < 0 {
+		return s
+	}
+	b := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			switch s[i+1] {
+			case 'n':
+				b = append(b, '\n')
+				i++
+				continue
+			case 'r':
+				b = append(b, '\r')
+				i++
+				continue
+			case '\\', '|':
+				b = append(b, s[i+1])
+				i++
+				continue
+			}
+		}
+		b = append(b, s[i])
+	}
+	return string(b)
+}
+
+// ParseGlogEntry decodes one line written by GlogFmtEntry. Glog timestamps
+// carry no year or zone, so the entry is placed in the most recent year
+// that keeps it from being in the future. Like all entries, they are UTC.
+func ParseGlogEntry(data []byte) (Entry, error) {
+	line := strings.TrimSuffix(string(data), "\n")
+	invalid := fmt.Errorf("invalid glog entry: %.40q", line)
+	// Lmmdd hh:mm:ss.uuuuuu pid source] message | {json}
+	if len(line) < 22 || line[21] != ' ' {
+		return nil, invalid
+	}
+	level, ok := levelTableNow().byChar[line[0]]
+	if !ok {
+		return nil, invalid
+	}
+	de := &decodedEntry{level: level}
+	ts, err := time.ParseInLocation("0102 15:04:05.000000", line[1:21], time.UTC)
+	if err != nil {
+		return nil, invalid
+	}
+	nowTime := now().UTC()
+	ts = ts.AddDate(nowTime.Year(), 0, 0)
+	if ts.After(nowTime.Add(24 * time.Hour)) {
+		ts = ts.AddDate(-1, 0, 0)
+	}
+	de.timestamp = ts
+
+	rest := line[22:]
+	sp := strings.IndexByte(rest, ' ')
+	if sp < 0 {
+		return nil, invalid
+	}
+	if de.pid, err = strconv.Atoi(rest[:sp]); err != nil {
+		return nil, invalid
+	}
+	rest = rest[sp+1:]
+	end := strings.Index(rest, "] ")
+	if end < 0 {
+		return nil, invalid
+	}
+	de.source, rest = rest[:end], rest[end+2:]
+
+	// The message has its pipes escaped, unlike field values, so the first
+	// separator ends it.
+	sep := strings.Index(rest, " | ")
+	if sep < 0 {
+		return nil, invalid
+	}
+	de.message = glogUnescape(rest[:sep])
+	st := struct {
+		Fields     Fields
+		Err        string
+		StackTrace []string
+	}{}
+	if err := json.Unmarshal([]byte(rest[sep+3:]), &st); err != nil {
+		return nil, err
+	}
+	de.fields, de.stack = st.Fields, st.StackTrace
+	if st.Err != "" {
+		de.err = stdErrors.New(st.Err)
+	}
+	return de, nil
+}
@@ -4,7 +4,6 @@ import (
 	"flag"
 	"fmt"
 	"log"
-	"os"
 
 	"github.com/msolo/go-bis/slog"
 	"github.com/pkg/errors"
@@ -25,25 +24,17 @@ func (le logEntry) Fields() slog.Fields {
 }
 
 func main() {
-	logFmt := flag.String("log.fmt", "", "Set log format.")
 	cfg := &slog.Config{}
 	slog.RegisterFlags(flag.CommandLine, cfg)
 	flag.Parse()
 
 	slog.CopyStandardLogTo("WARN")
 
-	fmtEntry := slog.GlogFmtEntry
-	if *logFmt == "json" {
-		fmtEntry = slog.JsonFmtEntry
-	}
-
-	f, err := os.OpenFile(cfg.Fname, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	closer, err := slog.Setup(cfg)
 	if err != nil {
 		log.Fatalln(err)
 	}
-
-	logH := slog.NewHandler(f, fmtEntry)
-	slog.SetHandler(slog.NewLevelHandler(logH, cfg))
+	defer closer.Close()
 
 	log.Printf("system logger printf")
 
@@ -0,0 +1,88 @@
+package slog
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Enabler is implemented by Handlers that can tell ahead of time whether
+// an entry at level from the named logger, or an unnamed one if logger is
+// empty, might be written. Handlers without it are assumed to write
+// everything. Answers may err on the side of true.
+type Enabler interface {
+	Enabled(level Level, logger string) bool
+}
+
+func handlerEnabled(h Handler, level Level, logger string) bool {
+	switch h := h.(type) {
+	case *slogger:
+		// A Slogger used as a Handler; its Enabled method takes no name.
+		return h.enabled(level, logger)
+	case Enabler:
+		return h.Enabled(level, logger)
+	}
+	return true
+}
+
+// loggerName returns the name set by Named, if any.
+func loggerName(f Fields) string {
+	name, _ := f[LoggerField].(string)
+	return name
+}
+
+func (lg *slogger) Enabled(level Level) bool {
+	return lg.enabled(level, "")
+}
+
+func (lg *slogger) enabled(level Level, logger string) bool {
+	lg.mu.Lock()
+	h := lg.h
+	lg.mu.Unlock()
+	return handlerEnabled(h, level, logger)
+}
+
+// Enabled reports whether an entry at level might be written, so callers
+// can skip building expensive fields:
+//
+//	if lg.Enabled(slog.DebugLevel) {
+//		lg.WithFields(slog.Fields{"plan": plan.Explain()}).Info("query")
+//	}
+//
+// The answer takes the level configured for this logger's name into
+// account; see NameLevels.
+func (esl *entrySlogger) Enabled(level Level) bool {
+	return handlerEnabled(esl.handler, level, loggerName(esl.fields))
+}
+
+// Lazy is a field value computed only if its entry will be written, for
+// example slog.Lazy(func() interface{} { return dump(state) }). Sloggers
+// evaluate it once, on the logging goroutine, when the entry is created.
+// Entries passed straight to a Handler are not resolved; their Lazy values
+// are evaluated whenever a formatter renders them.
+type Lazy func() interface{}
+
+func (lz Lazy) MarshalJSON() ([]byte, error) {
+	return json.Marshal(lz())
+}
+
+func (lz Lazy) String() string {
+	return fmt.Sprint(lz())
+}
+
+// resolveLazy returns e with any Lazy field values evaluated.
+func resolveLazy(e Entry) Entry {
+	f := e.Fields()
+	var resolved Fields
+	for k, v := range f {
+		if lz, ok := v.(Lazy); ok {
+			if resolved == nil {
+				resolved = mergeFields(nil, f)
+			}
+			resolved[k] = lz()
+		}
+	}
+	if resolved == nil {
+		return e
+	}
+	return &EntryView{Entry: e, FieldsFunc: func() Fields { return resolved }}
+}
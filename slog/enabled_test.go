@@ -0,0 +1,47 @@
+package slog
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEnabledAndLazy(t *testing.T) {
+	slog, lw := testSlog()
+	slog.cfg.Level = WarnLevel
+	if slog.Enabled(InfoLevel) || !slog.Enabled(WarnLevel) {
+		t.Error("Enabled disagrees with Config.Level")
+	}
+	db := slog.Named("db")
+	http := slog.Named("http")
+	slog.cfg.Levels = NameLevels{"db": DebugLevel, "http": ErrorLevel}
+	if !db.Enabled(DebugLevel) || !db.Named("pool").Enabled(DebugLevel) {
+		t.Error("Enabled ignores the logger's own level")
+	}
+	if slog.Enabled(DebugLevel) || http.Enabled(WarnLevel) || !http.Enabled(ErrorLevel) {
+		t.Error("Enabled applies another logger's level")
+	}
+
+	calls := 0
+	expensive := Lazy(func() interface{} {
+		calls++
+		return "computed"
+	})
+	slog.WithFields(Fields{"plan": expensive}).Info("filtered")
+	if calls != 0 {
+		t.Error("lazy value computed for a filtered entry")
+	}
+	slog.WithFields(Fields{"plan": expensive}).Warn("written")
+	if calls != 1 {
+		t.Errorf("lazy value computed %d times", calls)
+	}
+	lastLine, _ := lw.LastLine()
+	if !strings.Contains(lastLine, `"plan":"computed"`) {
+		t.Errorf("lazy value not rendered: %s", lastLine)
+	}
+
+	split := NewSplitHandler(NewLevelHandler(HandlerFunc(func(Entry) error { return nil }), &Config{Level: ErrorLevel}),
+		HandlerFunc(func(Entry) error { return nil }), WarnLevel)
+	if handlerEnabled(split, InfoLevel, "") || !handlerEnabled(split, WarnLevel, "") {
+		t.Error("split handler Enabled wrong")
+	}
+}
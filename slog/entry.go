@@ -22,6 +22,12 @@ type entry struct {
 }
 
 func (ent *entry) MarshalJSON() ([]byte, error) {
+	return marshalEntryJSON(ent)
+}
+
+// marshalEntryJSON encodes any Entry the same way, so wrapped entries
+// serialize identically to the ones built by this package.
+func marshalEntryJSON(e Entry) ([]byte, error) {
 	st := struct {
 		Level      Level
 		Timestamp  time.Time
@@ -29,18 +35,18 @@ func (ent *entry) MarshalJSON() ([]byte, error) {
 		Pid        int
 		Source     string
 		Message    string
-		Fields     Fields            `json:",omitempty"`
-		Err        string            `json:",omitempty"`
-		StackTrace errors.StackTrace `json:",omitempty"`
-	}{ent.level,
-		ent.timeStarted,
-		ent.hostname,
-		ent.pid,
-		ent.source,
-		ent.message,
-		ent.Fields(),
-		maybeErrString(ent.err),
-		ent.StackTrace(),
+		Fields     Fields      `json:",omitempty"`
+		Err        string      `json:",omitempty"`
+		StackTrace interface{} `json:",omitempty"`
+	}{e.Level(),
+		e.Timestamp(),
+		e.Hostname(),
+		e.Pid(),
+		e.Source(),
+		e.Message(),
+		e.Fields(),
+		maybeErrString(e.Err()),
+		entryStack(e),
 	}
 	return json.Marshal(st)
 }
@@ -76,6 +82,38 @@ func (ent *entry) StackTrace() errors.StackTrace {
 	return nil
 }
 
+// stackTexter is implemented by entries read back from storage, whose
+// stack frames survive only as text.
+type stackTexter interface {
+	Stack() []string
+}
+
+// entryStack returns whichever form of stack trace e carries, or nil.
+func entryStack(e Entry) interface{} {
+	if st := e.StackTrace(); st != nil {
+		return st
+	}
+	if stx, ok := e.(stackTexter); ok && len(stx.Stack()) > 0 {
+		return stx.Stack()
+	}
+	return nil
+}
+
+// entryStackText returns the stack trace of e as "func file:line" frames.
+func entryStackText(e Entry) []string {
+	var stack []string
+	switch st := entryStack(e).(type) {
+	case []string:
+		stack = st
+	case nil:
+	default:
+		// errors.StackTrace frames render as "func file:line" text.
+		data, _ := json.Marshal(st)
+		json.Unmarshal(data, &stack)
+	}
+	return stack
+}
+
 func maybeErrString(err error) string {
 	if err != nil {
 		return err.Error()
@@ -121,8 +159,8 @@ func (esl *entrySlogger) WithError(err error) Slogger {
 func (esl *entrySlogger) WithFielder(f Fielder) Slogger {
 	esl2 := entrySlogger{}
 	esl2 = *esl
-	fs := make([]Fielder, 0, 4)
-	copy(fs, esl.fielders)
+	fs := make([]Fielder, 0, len(esl.fielders)+1)
+	fs = append(fs, esl.fielders...)
 	fs = append(fs, f)
 	esl2.fielders = fs
 	return &esl2
@@ -170,8 +208,22 @@ func (esl *entrySlogger) Errorf(format string, args ...interface{}) {
 	esl.log(ErrorLevel, fmt.Sprintf(format, args...))
 }
 
+func (esl *entrySlogger) Log(level Level, args ...interface{}) {
+	esl.log(level, fmt.Sprint(args...))
+}
+
+func (esl *entrySlogger) Logf(level Level, format string, args ...interface{}) {
+	esl.log(level, fmt.Sprintf(format, args...))
+}
+
 func (esl *entrySlogger) log(level Level, msg string) {
-	ent := &esl.entry
+	if !esl.Enabled(level) {
+		return
+	}
+	// Copy so a shared Slogger can log concurrently and handlers may
+	// retain the entry, as AsyncHandler does.
+	ent := &entry{}
+	*ent = esl.entry
 	ent.timeStarted = now().UTC()
 	ent.level = level
 	ent.message = msg
@@ -182,7 +234,7 @@ func (esl *entrySlogger) log(level Level, msg string) {
 		file, line := source(0)
 		ent.source = fmt.Sprintf("%s:%d", file, line)
 	}
-	if err := esl.handler.WriteEntry(ent); err != nil {
+	if err := esl.handler.WriteEntry(resolveLazy(ent)); err != nil {
 		println("log write failed:", err.Error())
 	}
 }
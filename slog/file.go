@@ -0,0 +1,205 @@
+package slog
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/msolo/go-bis/ioutil2"
+)
+
+// RotateConfig controls when a log file is rolled over. The zero value
+// never rotates.
+type RotateConfig struct {
+	// Rotate before a write would grow the file past this many bytes.
+	MaxBytes int64
+	// Rotate before a write once the file has been open this long.
+	MaxAge time.Duration
+	// Keep at most this many rotated files; 0 keeps them all.
+	MaxBackups int
+	// Header, if set, returns bytes to start each new file with, including
+	// the first one.
+	Header func() ([]byte, error)
+}
+
+// RotatingFile is an append-only log file that renames itself aside with a
+// timestamp suffix and starts over when it grows too large or too old.
+type RotatingFile struct {
+	mu       sync.Mutex
+	path     string
+	perm     os.FileMode
+	cfg      RotateConfig
+	f        *os.File
+	size     int64
+	openedAt time.Time
+	// Set when a rotation fails, to delay the next attempt.
+	rotateFailedAt time.Time
+}
+
+// OpenRotatingFile opens path for appending, creating it if necessary.
+func OpenRotatingFile(path string, perm os.FileMode, cfg RotateConfig) (*RotatingFile, error) {
+	rf := &RotatingFile{path: path, perm: perm, cfg: cfg}
+	if err := rf.open(); err != nil {
+		return nil, err
+	}
+	return rf, nil
+}
+
+// open opens rf.path and writes the header to a new file. rf is only
+// changed on success.
+func (rf *RotatingFile) open() error {
+	f, err := os.OpenFile(rf.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, rf.perm)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	size := fi.Size()
+	// The file may have just been created; make sure its directory entry
+	// is durable before anyone depends on what is written to it.
+	if size == 0 {
+		if err := ioutil2.SyncDir(filepath.Dir(rf.path)); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if size == 0 && rf.cfg.Header != nil {
+		header, err := rf.cfg.Header()
+		if err == nil {
+			var n int
+			n, err = f.Write(header)
+			size += int64(n)
+		}
+		if err != nil {
+			f.Close()
+			return err
+		}
+	}
+	rf.f = f
+	rf.size = size
+	rf.openedAt = time.Now()
+	return nil
+}
+
+// After a failed rotation, keep appending to the current file for a while
+// before trying again.
+const rotateRetryDelay = 10 * time.Second
+
+func (rf *RotatingFile) Write(data []byte) (n int, err error) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.needsRotate(len(data)) {
+		if err := rf.rotate(); err != nil {
+			// The entry is still written below; losing it would be worse
+			// than an oversized file.
+			println("log rotation failed:", err.Error())
+		}
+	}
+	n, err = rf.f.Write(data)
+	rf.size += int64(n)
+	return n, err
+}
+
+func (rf *RotatingFile) needsRotate(n int) bool {
+	if rf.size == 0 || time.Since(rf.rotateFailedAt) < rotateRetryDelay {
+		return false
+	}
+	if rf.cfg.MaxBytes > 0 && rf.size+int64(n) > rf.cfg.MaxBytes {
+		return true
+	}
+	return rf.cfg.MaxAge > 0 && time.Since(rf.openedAt) >= rf.cfg.MaxAge
+}
+
+// Rotate moves the current file aside and opens a fresh one, regardless
+// of the configured limits. This is handy from a SIGHUP handler. If it
+// fails, writes continue to the current file.
+func (rf *RotatingFile) Rotate() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	return rf.rotate()
+}
+
+func (rf *RotatingFile) rotate() (err error) {
+	defer func() {
+		if err != nil {
+			rf.rotateFailedAt = time.Now()
+		}
+	}()
+	// Rotation is rare, so always flush what was written to the old file.
+	if err := fsync(rf.f); err != nil {
+		return err
+	}
+	old := rf.f
+	backup := rf.path + "." + time.Now().UTC().Format("20060102-150405.000000000")
+	if err := os.Rename(rf.path, backup); err != nil {
+		return err
+	}
+	if err := rf.open(); err != nil {
+		// Put the old file back so writes through rf.f land at rf.path.
+		if renameErr := os.Rename(backup, rf.path); renameErr != nil {
+			return fmt.Errorf("%v; restoring %s also failed: %v", err, rf.path, renameErr)
+		}
+		return err
+	}
+	old.Close()
+	return rf.prune()
+}
+
+// prune removes the oldest backups beyond MaxBackups. The timestamp
+// suffix sorts lexically in time order.
+func (rf *RotatingFile) prune() error {
+	if rf.cfg.MaxBackups <= 0 {
+		return nil
+	}
+	backups, err := filepath.Glob(rf.path + ".[0-9]*")
+	if err != nil {
+		return err
+	}
+	sort.Strings(backups)
+	for len(backups) > rf.cfg.MaxBackups {
+		if err := os.Remove(backups[0]); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		backups = backups[1:]
+	}
+	return nil
+}
+
+// Name returns the path of the active file.
+func (rf *RotatingFile) Name() string {
+	return rf.path
+}
+
+// Sync flushes the active file. It doesn't hold the lock during the
+// fsync, so writes can continue meanwhile.
+func (rf *RotatingFile) Sync() error {
+	rf.mu.Lock()
+	f := rf.f
+	rf.mu.Unlock()
+	err := fsync(f)
+	if err != nil {
+		rf.mu.Lock()
+		rotated := rf.f != f
+		rf.mu.Unlock()
+		if rotated {
+			// Closed by a rotation, which synced it first.
+			return nil
+		}
+	}
+	return err
+}
+
+func (rf *RotatingFile) Close() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	return rf.f.Close()
+}
+
+// fsync is replaced in tests to count or slow down flushes.
+var fsync = (*os.File).Sync
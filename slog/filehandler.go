@@ -0,0 +1,218 @@
+package slog
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SyncPolicy selects when a FileHandler calls fsync.
+type SyncPolicy int
+
+const (
+	// SyncNever leaves flushing to the operating system.
+	SyncNever SyncPolicy = iota
+	// SyncPeriodic flushes in the background every FileConfig.SyncInterval.
+	SyncPeriodic
+	// SyncOnError makes entries at ErrorLevel and above durable before
+	// WriteEntry returns.
+	SyncOnError
+	// SyncAlways makes every entry durable before WriteEntry returns.
+	SyncAlways
+	maxSyncPolicies
+)
+
+var syncPolicyName = [maxSyncPolicies]string{
+	"never",
+	"periodic",
+	"error",
+	"always",
+}
+
+func (sp *SyncPolicy) Set(val string) error {
+	for i, name := range syncPolicyName {
+		if strings.EqualFold(name, val) {
+			*sp = SyncPolicy(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid sync policy: %s", val)
+}
+
+func (sp *SyncPolicy) String() string {
+	if *sp < 0 || *sp >= maxSyncPolicies {
+		return fmt.Sprintf("SyncPolicy(%d)", int(*sp))
+	}
+	return syncPolicyName[int(*sp)]
+}
+
+const defaultSyncInterval = time.Second
+
+type FileConfig struct {
+	Sync SyncPolicy
+	// Used by SyncPeriodic; defaults to one second.
+	SyncInterval time.Duration
+	Rotate       RotateConfig
+}
+
+// FileHandler writes formatted entries to a RotatingFile with selectable
+// durability. Fsyncs run on a background goroutine; writers that need
+// durability wait for the next one, so concurrent writers share a single
+// fsync (group commit).
+type FileHandler struct {
+	mu       sync.Mutex
+	rf       *RotatingFile
+	fmtEntry FmtEntry
+	cfg      FileConfig
+
+	syncMu    sync.Mutex
+	synced    *sync.Cond
+	written   uint64 // sequence of the last write, guarded by mu
+	syncedSeq uint64
+	syncErr   error
+	closed    bool // no more sync requests may be sent
+	stopped   bool // the final sync is done
+	requests  chan struct{}
+	done      chan struct{}
+}
+
+// OpenFileHandler opens path for appending and writes entries formatted by
+// fmtEntry according to cfg.
+func OpenFileHandler(path string, fmtEntry FmtEntry, cfg FileConfig) (*FileHandler, error) {
+	rf, err := OpenRotatingFile(path, 0666, cfg.Rotate)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.SyncInterval <= 0 {
+		cfg.SyncInterval = defaultSyncInterval
+	}
+	fh := &FileHandler{
+		rf:       rf,
+		fmtEntry: fmtEntry,
+		cfg:      cfg,
+		requests: make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+	fh.synced = sync.NewCond(&fh.syncMu)
+	go fh.syncLoop()
+	return fh, nil
+}
+
+func (fh *FileHandler) syncLoop() {
+	defer close(fh.done)
+	var tick <-chan time.Time
+	if fh.cfg.Sync == SyncPeriodic {
+		ticker := time.NewTicker(fh.cfg.SyncInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case _, ok := <-fh.requests:
+			if !ok {
+				fh.sync()
+				fh.syncMu.Lock()
+				fh.stopped = true
+				fh.synced.Broadcast()
+				fh.syncMu.Unlock()
+				return
+			}
+		case <-tick:
+		}
+		fh.sync()
+	}
+}
+
+// sync flushes everything written so far and wakes the writers waiting
+// on it.
+func (fh *FileHandler) sync() {
+	fh.mu.Lock()
+	seq := fh.written
+	fh.mu.Unlock()
+
+	fh.syncMu.Lock()
+	upToDate := seq <= fh.syncedSeq
+	fh.syncMu.Unlock()
+	if upToDate {
+		return
+	}
+
+	err := fh.rf.Sync()
+	fh.syncMu.Lock()
+	fh.syncedSeq = seq
+	fh.syncErr = err
+	fh.synced.Broadcast()
+	fh.syncMu.Unlock()
+}
+
+func (fh *FileHandler) needsSync(e Entry) bool {
+	switch fh.cfg.Sync {
+	case SyncAlways:
+		return true
+	case SyncOnError:
+		return e.Level() >= ErrorLevel
+	}
+	return false
+}
+
+func (fh *FileHandler) WriteEntry(e Entry) error {
+	data := fh.fmtEntry(e)
+	fh.mu.Lock()
+	_, err := io.WriteString(fh.rf, data)
+	fh.written++
+	seq := fh.written
+	fh.mu.Unlock()
+	if err != nil || !fh.needsSync(e) {
+		return err
+	}
+
+	fh.syncMu.Lock()
+	defer fh.syncMu.Unlock()
+	if !fh.closed {
+		select {
+		case fh.requests <- struct{}{}:
+		default:
+			// A sync is already pending and will cover this write.
+		}
+	}
+	for fh.syncedSeq < seq && !fh.stopped {
+		fh.synced.Wait()
+	}
+	return fh.syncErr
+}
+
+// Sync flushes all entries written so far.
+func (fh *FileHandler) Sync() error {
+	return fh.rf.Sync()
+}
+
+// Rotate starts a new file immediately.
+func (fh *FileHandler) Rotate() error {
+	fh.mu.Lock()
+	defer fh.mu.Unlock()
+	return fh.rf.Rotate()
+}
+
+// Close flushes outstanding entries, regardless of policy, and closes the
+// file.
+func (fh *FileHandler) Close() error {
+	fh.syncMu.Lock()
+	if fh.closed {
+		fh.syncMu.Unlock()
+		return nil
+	}
+	fh.closed = true
+	close(fh.requests)
+	fh.syncMu.Unlock()
+
+	<-fh.done
+	fh.syncMu.Lock()
+	err := fh.syncErr
+	fh.syncMu.Unlock()
+	if closeErr := fh.rf.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,79 @@
+package slog
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFileHandlerGroupCommit(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// A slow fsync gives writers time to pile up behind it.
+	var syncs int64
+	defer func(orig func(*os.File) error) { fsync = orig }(fsync)
+	fsync = func(f *os.File) error {
+		atomic.AddInt64(&syncs, 1)
+		time.Sleep(time.Millisecond)
+		return f.Sync()
+	}
+
+	for _, policy := range []SyncPolicy{SyncNever, SyncPeriodic, SyncOnError, SyncAlways} {
+		atomic.StoreInt64(&syncs, 0)
+		fname := filepath.Join(tmpDir, policy.String()+".log")
+		fh, err := OpenFileHandler(fname, GlogFmtEntry, FileConfig{Sync: policy})
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg := &Config{}
+		slog := &slogger{h: fh, cfg: cfg}
+
+		wg := sync.WaitGroup{}
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 25; j++ {
+					slog.Info("durable")
+					slog.Error("more durable")
+				}
+			}()
+		}
+		wg.Wait()
+		if err := fh.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := ioutil.ReadFile(fname)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := bytes.Count(data, []byte("\n")); n != 400 {
+			t.Errorf("%s: expected 400 lines, found %d", policy.String(), n)
+		}
+		n := atomic.LoadInt64(&syncs)
+		switch policy {
+		case SyncNever:
+			if n != 1 {
+				t.Errorf("%s: expected only the final sync, got %d", policy.String(), n)
+			}
+		case SyncOnError:
+			if n == 0 || n >= 100 {
+				t.Errorf("%s: expected grouped syncs for 200 errors, got %d", policy.String(), n)
+			}
+		case SyncAlways:
+			if n == 0 || n >= 200 {
+				t.Errorf("%s: expected grouped syncs for 400 writes, got %d", policy.String(), n)
+			}
+		}
+	}
+}
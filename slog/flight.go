@@ -0,0 +1,88 @@
+package slog
+
+import (
+	"fmt"
+	"sync"
+)
+
+type recordedEntry struct {
+	e   Entry
+	key string
+}
+
+// FlightRecorder keeps the most recent entries at every level in memory
+// but only passes entries at or above the configured level on to its
+// handler. When an error arrives, the buffered entries that share its key
+// are written first, so verbose context shows up exactly when something
+// fails.
+type FlightRecorder struct {
+	mu       sync.Mutex
+	h        Handler
+	cfg      *Config
+	keyField string
+	ring     []recordedEntry
+	next     int
+}
+
+// NewFlightRecorder buffers the last size entries in front of h. Entries
+// are grouped by the value of keyField, such as a request id; entries
+// without it share one group, so an error without the key brings out all
+// of them.
+func NewFlightRecorder(h Handler, cfg *Config, size int, keyField string) *FlightRecorder {
+	return &FlightRecorder{
+		h:        h,
+		cfg:      cfg,
+		keyField: keyField,
+		ring:     make([]recordedEntry, size),
+	}
+}
+
+func (fr *FlightRecorder) key(e Entry) string {
+	if fr.keyField != "" {
+		if v, ok := e.Fields()[fr.keyField]; ok {
+			return fmt.Sprint(v)
+		}
+	}
+	return ""
+}
+
+func (fr *FlightRecorder) wrappedHandlers() []Handler {
+	return []Handler{fr.h}
+}
+
+func (fr *FlightRecorder) WriteEntry(e Entry) error {
+	key := fr.key(e)
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+
+	if e.Level() >= ErrorLevel {
+		if err := fr.flush(key); err != nil {
+			return err
+		}
+	}
+	if e.Level() >= fr.cfg.Level {
+		return fr.h.WriteEntry(e)
+	}
+	if len(fr.ring) > 0 {
+		fr.ring[fr.next] = recordedEntry{e, key}
+		fr.next = (fr.next + 1) % len(fr.ring)
+	}
+	return nil
+}
+
+// flush writes out buffered entries for key, oldest first, and forgets
+// them so a later error does not repeat them.
+func (fr *FlightRecorder) flush(key string) error {
+	for i := range fr.ring {
+		re := &fr.ring[(fr.next+i)%len(fr.ring)]
+		if re.e == nil || re.key != key {
+			continue
+		}
+		e := re.e
+		*re = recordedEntry{}
+		if err := fr.h.WriteEntry(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package slog
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFlightRecorder(t *testing.T) {
+	cfg := &Config{Level: WarnLevel}
+	lw := &lineWriter{}
+	fr := NewFlightRecorder(NewHandler(lw, GlogFmtEntry), cfg, 4, "req")
+	slog := &slogger{h: fr, cfg: cfg}
+
+	reqA := slog.WithFields(Fields{"req": "a"})
+	reqB := slog.WithFields(Fields{"req": "b"})
+	reqA.Info("a1")
+	reqB.Info("b1")
+	reqA.Info("a2")
+	if len(lw.lines) != 0 {
+		t.Fatalf("entries below threshold written: %v", lw.lines)
+	}
+
+	reqA.Error("a failed")
+	if len(lw.lines) != 3 {
+		t.Fatalf("expected 2 buffered entries and the error, found %v", lw.lines)
+	}
+	for i, msg := range []string{"a1", "a2", "a failed"} {
+		if !strings.Contains(lw.lines[i], msg) {
+			t.Errorf("line %d: expected %q, found %q", i, msg, lw.lines[i])
+		}
+	}
+
+	reqA.Error("a failed again")
+	if len(lw.lines) != 4 {
+		t.Fatalf("buffered entries repeated: %v", lw.lines)
+	}
+}
+
+func TestFlightRecorderUnkeyed(t *testing.T) {
+	cfg := &Config{Level: WarnLevel}
+	lw := &lineWriter{}
+	fr := NewFlightRecorder(NewHandler(lw, GlogFmtEntry), cfg, 4, "req")
+	slog := &slogger{h: fr, cfg: cfg}
+
+	done := make(chan struct{})
+	go func() {
+		slog.Info("elsewhere")
+		close(done)
+	}()
+	<-done
+	slog.WithFields(Fields{"req": "a"}).Info("a1")
+	slog.Error("unkeyed failure")
+	if len(lw.lines) != 2 || !strings.Contains(lw.lines[0], "elsewhere") {
+		t.Fatalf("unkeyed entries should share one group: %v", lw.lines)
+	}
+}
@@ -0,0 +1,419 @@
+package slog
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/msolo/go-bis/ioutil2"
+)
+
+// DefaultFollowInterval is how often a followed file is checked for new
+// data once the reader has caught up.
+const DefaultFollowInterval = 250 * time.Millisecond
+
+// FollowOptions control Follow.
+type FollowOptions struct {
+	// OffsetFile, if set, records how far the log has been read so a later
+	// Follow can resume there. It is written atomically whenever the
+	// reader catches up and on Close, so entries read since the last save
+	// are read again after a crash, never skipped.
+	OffsetFile string
+	// FromEnd starts at the end of the file when there is no saved offset.
+	FromEnd bool
+	// Interval defaults to DefaultFollowInterval.
+	Interval time.Duration
+	// Parse decodes a line. By default lines starting with "{" are parsed
+	// with ParseJsonEntry and others with ParseGlogEntry.
+	Parse func([]byte) (Entry, error)
+}
+
+func parseLine(line []byte) (Entry, error) {
+	if bytes.HasPrefix(line, []byte("{")) {
+		return ParseJsonEntry(line)
+	}
+	return ParseGlogEntry(line)
+}
+
+// followOffset is the saved read position. The fingerprint, a hash of the
+// file's first line, identifies the file across renames.
+type followOffset struct {
+	Offset      int64  `json:"offset"`
+	Fingerprint string `json:"fingerprint"`
+}
+
+// Follower reads a log file as it is written, like tail -F. It follows
+// both rename-and-create rotation, finishing the old file before moving to
+// the new one, and copy-and-truncate rotation, starting over when the file
+// shrinks or its first line changes.
+//
+//	f, err := slog.Follow("/var/log/app.log", slog.FollowOptions{})
+//	for f.Next() {
+//		fmt.Print(slog.GlogFmtEntry(f.Entry()))
+//	}
+//	err = f.Err()
+type Follower struct {
+	path string
+	opts FollowOptions
+
+	mu          sync.Mutex
+	f           *os.File
+	rd          *bufio.Reader
+	offset      int64
+	fingerprint string
+	// Older files left to finish before opening path, when resuming.
+	pending []string
+	partial []byte
+	skipped int64
+	cur     Entry
+	err     error
+	closed  bool
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// Follow opens path for following, resuming from opts.OffsetFile when it
+// names a position in path or in one of its rotated backups. Backups are
+// taken in modification time order, which suits both RotatingFile's names
+// and logrotate's numbered ones; compressed backups are skipped.
+func Follow(path string, opts FollowOptions) (*Follower, error) {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultFollowInterval
+	}
+	if opts.Parse == nil {
+		opts.Parse = parseLine
+	}
+	fl := &Follower{path: path, opts: opts, done: make(chan struct{})}
+
+	saved := &followOffset{}
+	if opts.OffsetFile != "" {
+		data, err := ioutil.ReadFile(opts.OffsetFile)
+		if err == nil {
+			err = json.Unmarshal(data, saved)
+		}
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+
+	if saved.Fingerprint != "" {
+		backups, err := followBackups(path)
+		if err != nil {
+			return nil, err
+		}
+		files := append(backups, path)
+		for i, fname := range files {
+			if fileFingerprint(fname) == saved.Fingerprint {
+				fl.pending = files[i+1:]
+				return fl, fl.open(fname, saved.Offset)
+			}
+		}
+		// The file read last is gone; start the current one from the top.
+		return fl, fl.open(path, 0)
+	}
+	if opts.FromEnd {
+		return fl, fl.open(path, -1)
+	}
+	return fl, fl.open(path, 0)
+}
+
+// compressedSuffixes mark backups that can't be read as text.
+var compressedSuffixes = []string{".gz", ".bz2", ".xz", ".zst", ".lz4", ".Z", ".zip"}
+
+// followBackups returns the rotated backups of path, oldest first. Both
+// RotatingFile's timestamped names and logrotate's numbered ones, where .1
+// is the newest, are ordered by modification time. Compressed backups are
+// skipped.
+func followBackups(path string) ([]string, error) {
+	names, err := filepath.Glob(path + ".[0-9]*")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	type backup struct {
+		name  string
+		mtime time.Time
+	}
+	var backups []backup
+	for _, name := range names {
+		compressed := false
+		for _, suffix := range compressedSuffixes {
+			compressed = compressed || strings.HasSuffix(name, suffix)
+		}
+		if compressed {
+			continue
+		}
+		fi, err := os.Stat(name)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		backups = append(backups, backup{name, fi.ModTime()})
+	}
+	sort.SliceStable(backups, func(i, j int) bool {
+		return backups[i].mtime.Before(backups[j].mtime)
+	})
+	files := make([]string, len(backups))
+	for i, b := range backups {
+		files[i] = b.name
+	}
+	return files, nil
+}
+
+// fileFingerprint hashes the first line of fname, or returns "" if there
+// isn't a complete one yet.
+func fileFingerprint(fname string) string {
+	f, err := os.Open(fname)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	return fingerprint(f)
+}
+
+// fingerprint reads from the start of f without moving its offset.
+func fingerprint(f io.ReaderAt) string {
+	rd := bufio.NewReader(io.NewSectionReader(f, 0, math.MaxInt64))
+	line, err := rd.ReadBytes('\n')
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(line)
+	return hex.EncodeToString(sum[:])
+}
+
+// open starts reading fname at offset, or at its end if offset is
+// negative. A missing file is not an error; it is opened once it appears.
+func (fl *Follower) open(fname string, offset int64) error {
+	f, err := os.Open(fname)
+	if os.IsNotExist(err) {
+		fl.f, fl.rd, fl.offset, fl.fingerprint = nil, nil, 0, ""
+		return nil
+	} else if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	if offset < 0 {
+		offset = fi.Size()
+	} else if offset > fi.Size() {
+		// Truncated since the offset was saved.
+		offset = 0
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return err
+	}
+	fl.f, fl.rd, fl.offset = f, bufio.NewReader(f), offset
+	fl.fingerprint = fingerprint(f)
+	fl.partial = nil
+	return nil
+}
+
+// readLine returns the next complete line, or nil at the end of the data
+// written so far.
+func (fl *Follower) readLine() ([]byte, error) {
+	if fl.rd == nil {
+		return nil, nil
+	}
+	data, err := fl.rd.ReadBytes('\n')
+	fl.partial = append(fl.partial, data...)
+	if err == io.EOF {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	line := fl.partial
+	fl.partial = nil
+	fl.offset += int64(len(line))
+	if fl.fingerprint == "" {
+		fl.fingerprint = fingerprint(fl.f)
+	}
+	return line, nil
+}
+
+// next closes the current file and opens fname from the top. It returns
+// the current file's last line if it was left without a newline, as
+// nothing more will be written to it.
+func (fl *Follower) next(fname string) ([]byte, error) {
+	tail := fl.partial
+	fl.closeFile()
+	return tail, fl.open(fname, 0)
+}
+
+// checkRotation moves on to the next file once the current one has been
+// replaced, or starts over if it was truncated. It reports whether there
+// may be more to read, along with any final line of the file it left.
+func (fl *Follower) checkRotation() (tail []byte, more bool, err error) {
+	if len(fl.pending) > 0 {
+		// Resuming in a backup, which can't grow; move on at EOF.
+		fname := fl.pending[0]
+		fl.pending = fl.pending[1:]
+		tail, err = fl.next(fname)
+		return tail, true, err
+	}
+	if fl.f == nil {
+		if err := fl.open(fl.path, 0); err != nil {
+			return nil, false, err
+		}
+		return nil, fl.f != nil, nil
+	}
+	fi, err := os.Stat(fl.path)
+	if os.IsNotExist(err) {
+		// Rotated away and not yet replaced.
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+	cur, err := fl.f.Stat()
+	if err != nil {
+		return nil, false, err
+	}
+	if !os.SameFile(fi, cur) {
+		// Renamed. This is only called at EOF, but the writer may have
+		// finished the old file since, so drain it once more first.
+		if cur.Size() > fl.offset+int64(len(fl.partial)) {
+			return nil, true, nil
+		}
+		tail, err = fl.next(fl.path)
+		return tail, true, err
+	}
+	if cur.Size() < fl.offset+int64(len(fl.partial)) || fingerprint(fl.f) != fl.fingerprint {
+		// Copied and truncated, perhaps already written past the offset.
+		if _, err := fl.f.Seek(0, io.SeekStart); err != nil {
+			return nil, false, err
+		}
+		fl.rd.Reset(fl.f)
+		fl.offset, fl.partial = 0, nil
+		fl.fingerprint = fingerprint(fl.f)
+		return nil, true, nil
+	}
+	return nil, false, nil
+}
+
+func (fl *Follower) closeFile() {
+	if fl.f != nil {
+		fl.f.Close()
+		fl.f, fl.rd = nil, nil
+	}
+}
+
+func (fl *Follower) saveOffset() error {
+	if fl.opts.OffsetFile == "" || fl.fingerprint == "" {
+		return nil
+	}
+	data, err := json.Marshal(&followOffset{Offset: fl.offset, Fingerprint: fl.fingerprint})
+	if err != nil {
+		return err
+	}
+	return ioutil2.WriteFileAtomic(fl.opts.OffsetFile, append(data, '\n'), 0644)
+}
+
+// Next waits for the next entry and reports whether there is one. It
+// returns false once the Follower is closed or fails. Lines that can't be
+// parsed are skipped; see Skipped.
+func (fl *Follower) Next() bool {
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+	for !fl.closed && fl.err == nil {
+		line, err := fl.readLine()
+		if err != nil {
+			fl.err = err
+			return false
+		}
+		if line == nil {
+			tail, more, err := fl.checkRotation()
+			if err != nil {
+				fl.err = err
+				return false
+			}
+			if len(tail) == 0 {
+				if !more {
+					if err := fl.wait(); err != nil {
+						fl.err = err
+						return false
+					}
+				}
+				continue
+			}
+			line = tail
+		}
+		e, err := fl.opts.Parse(bytes.TrimSuffix(line, []byte("\n")))
+		if err != nil {
+			fl.skipped++
+			continue
+		}
+		fl.cur = e
+		return true
+	}
+	return false
+}
+
+// wait saves the offset and sleeps until the next check, releasing the
+// lock so Close can proceed.
+func (fl *Follower) wait() error {
+	if err := fl.saveOffset(); err != nil {
+		return err
+	}
+	fl.mu.Unlock()
+	defer fl.mu.Lock()
+	timer := time.NewTimer(fl.opts.Interval)
+	defer timer.Stop()
+	select {
+	case <-fl.done:
+	case <-timer.C:
+	}
+	return nil
+}
+
+// Entry returns the current entry.
+func (fl *Follower) Entry() Entry {
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+	return fl.cur
+}
+
+// Skipped returns the number of lines that could not be parsed.
+func (fl *Follower) Skipped() int64 {
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+	return fl.skipped
+}
+
+// Err returns the error that stopped Next, if any.
+func (fl *Follower) Err() error {
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+	return fl.err
+}
+
+// Close stops a pending Next, saves the offset and closes the file. It is
+// safe to call from another goroutine.
+func (fl *Follower) Close() error {
+	fl.closeOnce.Do(func() { close(fl.done) })
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+	if fl.closed {
+		return nil
+	}
+	fl.closed = true
+	err := fl.saveOffset()
+	fl.closeFile()
+	return err
+}
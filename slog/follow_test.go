@@ -0,0 +1,224 @@
+package slog
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseGlogEntry(t *testing.T) {
+	e := NewEntry(WarnLevel, "disk | nearly full").
+		SetSource("disk.go:12").
+		SetFields(Fields{"pct": 97.0}).
+		SetErr(errors.New("ENOSPC"))
+	line := GlogFmtEntry(e)
+	de, err := ParseGlogEntry([]byte(line))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !de.Timestamp().Equal(e.Timestamp().Truncate(time.Microsecond)) {
+		t.Errorf("timestamp: got %v, want %v", de.Timestamp(), e.Timestamp())
+	}
+	if de.Level() != WarnLevel || de.Source() != "disk.go:12" || de.Message() != "disk | nearly full" ||
+		de.Err().Error() != "ENOSPC" || !reflect.DeepEqual(de.Fields(), Fields{"pct": 97.0}) || de.Pid() != pid {
+		t.Errorf("round trip mismatch: %q", JsonFmtEntry(de))
+	}
+	// Messages stay on one line and round trip exactly.
+	for _, msg := range []string{"first\nsecond", `C:\temp\new`, "trailing \\", "crlf\r\n", "a | b", `\|`} {
+		line := GlogFmtEntry(NewEntry(InfoLevel, msg))
+		if strings.Count(line, "\n") != 1 {
+			t.Errorf("%q: not one line: %q", msg, line)
+		}
+		if de, err := ParseGlogEntry([]byte(line)); err != nil || de.Message() != msg {
+			t.Errorf("%q: round trip gave %v, %v", msg, de, err)
+		}
+	}
+	// Field values are JSON and may hold the separator.
+	e = NewEntry(InfoLevel, "ran | piped").SetFields(Fields{"cmd": "ls | wc"})
+	de, err = ParseGlogEntry([]byte(GlogFmtEntry(e)))
+	if err != nil || de.Message() != "ran | piped" || !reflect.DeepEqual(de.Fields(), Fields{"cmd": "ls | wc"}) {
+		t.Errorf("separator in field: got %v, %v", de, err)
+	}
+	if _, err := ParseGlogEntry([]byte("not a glog line")); err == nil {
+		t.Error("expected error for invalid line")
+	}
+}
+
+// follow reads messages from fl in the background until it is closed.
+func follow(fl *Follower) <-chan string {
+	c := make(chan string, 100)
+	go func() {
+		for fl.Next() {
+			c <- fl.Entry().Message()
+		}
+		close(c)
+	}()
+	return c
+}
+
+func expectMessages(t *testing.T, c <-chan string, msgs ...string) {
+	t.Helper()
+	for _, want := range msgs {
+		select {
+		case got := <-c:
+			if got != want {
+				t.Fatalf("got %q, want %q", got, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %q", want)
+		}
+	}
+}
+
+func TestFollow(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	fname := filepath.Join(tmpDir, "app.log")
+	opts := FollowOptions{OffsetFile: filepath.Join(tmpDir, "app.offset"), Interval: 5 * time.Millisecond}
+
+	appendTo := func(fname string, data string) {
+		f, err := os.OpenFile(fname, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if _, err := f.WriteString(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	json := func(msg string) string {
+		return JsonFmtEntry(NewEntry(InfoLevel, msg))
+	}
+	glog := func(msg string) string {
+		return GlogFmtEntry(NewEntry(InfoLevel, msg))
+	}
+
+	appendTo(fname, json("one")+glog("two")+"garbage\n")
+	fl, err := Follow(fname, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := follow(fl)
+	expectMessages(t, c, "one", "two")
+
+	// A line is only read once it is complete.
+	line := json("three")
+	appendTo(fname, line[:10])
+	time.Sleep(20 * time.Millisecond)
+	appendTo(fname, line[10:])
+	expectMessages(t, c, "three")
+
+	// Rename and create; the old file is finished first.
+	if err := os.Rename(fname, fname+".1"); err != nil {
+		t.Fatal(err)
+	}
+	appendTo(fname+".1", json("four"))
+	appendTo(fname, json("five"))
+	expectMessages(t, c, "four", "five")
+
+	// Copy and truncate.
+	if err := os.Truncate(fname, 0); err != nil {
+		t.Fatal(err)
+	}
+	appendTo(fname, json("six"))
+	expectMessages(t, c, "six")
+
+	if err := fl.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-c; ok {
+		t.Fatal("Next returned an entry after Close")
+	}
+	if n := fl.Skipped(); n != 1 {
+		t.Errorf("expected 1 skipped line, got %d", n)
+	}
+
+	// Resume from the saved offset across a rotation that happened while
+	// nobody was following.
+	appendTo(fname, json("seven"))
+	if err := os.Rename(fname, fname+".2"); err != nil {
+		t.Fatal(err)
+	}
+	appendTo(fname, json("eight"))
+	fl, err = Follow(fname, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fl.Close()
+	c = follow(fl)
+	expectMessages(t, c, "seven", "eight")
+}
+
+func TestFollowLogrotateBackups(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	fname := filepath.Join(tmpDir, "app.log")
+	json := func(msgs ...string) []byte {
+		var data []byte
+		for _, msg := range msgs {
+			data = append(data, JsonFmtEntry(NewEntry(InfoLevel, msg))...)
+		}
+		return data
+	}
+
+	// logrotate numbers backups from .1, the newest; .10 sorts between .1
+	// and .2 by name but is the oldest.
+	start := time.Now().Add(-time.Hour)
+	for i, f := range []struct {
+		suffix string
+		data   []byte
+	}{
+		{".10", json("ten")},
+		{".3.gz", []byte("\x1f\x8b not text\n")},
+		{".2", json("two-a", "two-b")},
+		{".1", json("one")},
+		{"", json("current")},
+	} {
+		name := fname + f.suffix
+		if err := ioutil.WriteFile(name, f.data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		mtime := start.Add(time.Duration(i) * time.Minute)
+		if err := os.Chtimes(name, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	backups, err := followBackups(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{fname + ".10", fname + ".2", fname + ".1"}
+	if !reflect.DeepEqual(backups, want) {
+		t.Errorf("backups: got %q, want %q", backups, want)
+	}
+
+	// Resume after the first entry of .2.
+	opts := FollowOptions{OffsetFile: filepath.Join(tmpDir, "app.offset"), Interval: 5 * time.Millisecond}
+	offset := len(json("two-a"))
+	data := fmt.Sprintf(`{"offset":%d,"fingerprint":%q}`, offset, fileFingerprint(fname+".2"))
+	if err := ioutil.WriteFile(opts.OffsetFile, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fl, err := Follow(fname, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fl.Close()
+	expectMessages(t, follow(fl), "two-b", "one", "current")
+	if n := fl.Skipped(); n != 0 {
+		t.Errorf("skipped %d lines", n)
+	}
+}
@@ -0,0 +1,134 @@
+package slog
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Format selects how entries are rendered to a stream.
+type Format int
+
+const (
+	// AutoFormat uses ConsoleFormat on a terminal and GlogFormat otherwise.
+	AutoFormat Format = iota
+	ConsoleFormat
+	GlogFormat
+	JsonFormat
+	// BinaryFormat writes records for BinaryReader. Setup starts each file
+	// with BinaryHeader, and NewFormatHandler starts its stream with it
+	// before the first binary record.
+	BinaryFormat
+	maxFormats
+)
+
+var formatName = [maxFormats]string{
+	"auto",
+	"console",
+	"glog",
+	"json",
+	"binary",
+}
+
+func parseFormat(val string) (Format, error) {
+	for i, name := range formatName {
+		if strings.EqualFold(name, val) {
+			return Format(i), nil
+		}
+	}
+	return AutoFormat, fmt.Errorf("invalid log format: %s", val)
+}
+
+func (f *Format) Set(val string) (err error) {
+	*f, err = parseFormat(val)
+	return err
+}
+
+func (f Format) String() string {
+	if f < 0 || f >= maxFormats {
+		return fmt.Sprintf("Format(%d)", int(f))
+	}
+	return formatName[int(f)]
+}
+
+// FmtEntry returns the formatter for this format. AutoFormat is resolved
+// by whether wr is a terminal, and console output is only colored there.
+func (f Format) FmtEntry(wr io.Writer) FmtEntry {
+	return f.fmtEntry(isTerminal(wr))
+}
+
+func (f Format) fmtEntry(isTerm bool) FmtEntry {
+	switch f {
+	case ConsoleFormat:
+		if isTerm {
+			return ConsoleFmtEntry
+		}
+		return PlainConsoleFmtEntry
+	case JsonFormat:
+		return JsonFmtEntry
+	case BinaryFormat:
+		return BinaryFmtEntry
+	case AutoFormat:
+		if isTerm {
+			return ConsoleFmtEntry
+		}
+	}
+	return GlogFmtEntry
+}
+
+// fmtEntry returns the formatter for cfg.Format, applying cfg.JsonSchema
+// to JSON output and counting bytes in cfg.Metrics.
+func (cfg *Config) fmtEntry(isTerm bool) FmtEntry {
+	fmtEntry := cfg.Format.fmtEntry(isTerm)
+	if cfg.Format == JsonFormat && cfg.JsonSchema != DefaultSchema {
+		fmtEntry = cfg.JsonSchema.FmtEntry
+	}
+	if cfg.Metrics != nil {
+		fmtEntry = cfg.Metrics.FmtEntry(fmtEntry)
+	}
+	return fmtEntry
+}
+
+// isTerminal reports whether wr is a character device, which is a good
+// enough proxy for a TTY without pulling in ioctl plumbing.
+func isTerminal(wr io.Writer) bool {
+	f, ok := wr.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// formatHandler picks the formatter from cfg on every write so flags
+// parsed after the handler is installed still take effect.
+type formatHandler struct {
+	logHandler
+	cfg          *Config
+	isTerm       bool
+	binaryHeader bool
+}
+
+func (fh *formatHandler) WriteEntry(e Entry) error {
+	data := fh.cfg.fmtEntry(fh.isTerm)(e)
+	fh.mu.Lock()
+	defer fh.mu.Unlock()
+	wr := stdioWriter(fh.wr)
+	if fh.cfg.Format == BinaryFormat && !fh.binaryHeader {
+		if _, err := wr.Write(BinaryHeader()); err != nil {
+			return err
+		}
+		fh.binaryHeader = true
+	}
+	_, err := io.WriteString(wr, data)
+	return err
+}
+
+// NewFormatHandler writes entries to wr in the format named by cfg.Format.
+func NewFormatHandler(wr io.Writer, cfg *Config) Handler {
+	return &formatHandler{logHandler: logHandler{wr: wr}, cfg: cfg, isTerm: isTerminal(wr)}
+}
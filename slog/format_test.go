@@ -0,0 +1,87 @@
+package slog
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseFormat(t *testing.T) {
+	for _, name := range []string{"auto", "Console", "glog", "JSON", "binary"} {
+		var f Format
+		if err := f.Set(name); err != nil {
+			t.Errorf("%s: %v", name, err)
+		} else if f.String() != strings.ToLower(name) {
+			t.Errorf("%s: round trip gave %s", name, f.String())
+		}
+	}
+	var f Format
+	if err := f.Set("xml"); err == nil {
+		t.Error("expected error for unknown format")
+	}
+	if s := Format(42).String(); s != "Format(42)" {
+		t.Errorf("out of range format: %s", s)
+	}
+}
+
+func fmtName(fe FmtEntry) uintptr {
+	return reflect.ValueOf(fe).Pointer()
+}
+
+func TestFormatSelection(t *testing.T) {
+	for _, tc := range []struct {
+		format Format
+		isTerm bool
+		want   FmtEntry
+	}{
+		{AutoFormat, true, ConsoleFmtEntry},
+		{AutoFormat, false, GlogFmtEntry},
+		{ConsoleFormat, true, ConsoleFmtEntry},
+		{ConsoleFormat, false, PlainConsoleFmtEntry},
+		{GlogFormat, true, GlogFmtEntry},
+		{JsonFormat, true, JsonFmtEntry},
+		{BinaryFormat, false, BinaryFmtEntry},
+	} {
+		if got := tc.format.fmtEntry(tc.isTerm); fmtName(got) != fmtName(tc.want) {
+			t.Errorf("%s, isTerm=%v: wrong formatter", tc.format.String(), tc.isTerm)
+		}
+	}
+
+	rd, wr, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rd.Close()
+	defer wr.Close()
+	if isTerminal(wr) || isTerminal(&bytes.Buffer{}) {
+		t.Error("pipe or buffer reported as a terminal")
+	}
+	if fmtName(ConsoleFormat.FmtEntry(wr)) != fmtName(PlainConsoleFmtEntry) {
+		t.Error("console format colored on a pipe")
+	}
+}
+
+func TestConsoleFmtEntry(t *testing.T) {
+	e := NewEntry(ErrorLevel, "request failed").
+		SetSource("http.go:7").
+		SetFields(Fields{"path": "/a b", "code": 500}).
+		SetErr(errors.New("timeout"))
+
+	plain := PlainConsoleFmtEntry(e)
+	for _, want := range []string{"E ", "http.go:7", "request failed\n", "    code=500\n", "    err=timeout\n", `    path="/a b"`} {
+		if !strings.Contains(plain, want) {
+			t.Errorf("missing %q in %q", want, plain)
+		}
+	}
+	if strings.Contains(plain, "\x1b") {
+		t.Errorf("plain output has ANSI codes: %q", plain)
+	}
+
+	colored := ConsoleFmtEntry(e)
+	if !strings.HasPrefix(colored, levelColor(ErrorLevel)+"E"+ansiReset) {
+		t.Errorf("colored output lacks level color: %q", colored)
+	}
+}
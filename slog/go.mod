@@ -2,9 +2,13 @@ module github.com/msolo/go-bis/slog
 
 require (
 	github.com/apex/log v1.1.1
+	github.com/msolo/go-bis/ioutil2 v0.0.0-00010101000000-000000000000
 	github.com/pkg/errors v0.8.2-0.20190227000051-27936f6d90f9
-	github.com/sirupsen/logrus v1.4.2 // indirect
+	github.com/sirupsen/logrus v1.4.2
 	golang.org/x/tools v0.0.0-20191021224128-7178990c2503 // indirect
+	gopkg.in/yaml.v2 v2.2.8
 )
 
-go 1.13
+go 1.14
+
+replace github.com/msolo/go-bis/ioutil2 => ../ioutil2
@@ -0,0 +1,13 @@
+module github.com/msolo/go-bis/slog/grpclog
+
+require (
+	github.com/msolo/go-bis/slog v0.0.0-00010101000000-000000000000
+	google.golang.org/grpc v1.18.0
+)
+
+go 1.13
+
+replace (
+	github.com/msolo/go-bis/ioutil2 => ../../ioutil2
+	github.com/msolo/go-bis/slog => ../
+)
@@ -0,0 +1,253 @@
+// Package grpclog logs gRPC calls and gRPC's own diagnostics through slog.
+//
+// It is a separate module so that slog itself does not depend on gRPC.
+package grpclog
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/msolo/go-bis/slog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpcLog "google.golang.org/grpc/grpclog"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// codeLevel returns InfoLevel for success, WarnLevel for codes that
+// usually mean a bad request and ErrorLevel for server-side failures.
+func codeLevel(code codes.Code) slog.Level {
+	switch code {
+	case codes.OK:
+		return slog.InfoLevel
+	case codes.Canceled, codes.InvalidArgument, codes.NotFound, codes.AlreadyExists,
+		codes.PermissionDenied, codes.Unauthenticated, codes.ResourceExhausted,
+		codes.FailedPrecondition, codes.Aborted, codes.OutOfRange:
+		return slog.WarnLevel
+	}
+	return slog.ErrorLevel
+}
+
+func baseLogger(lg slog.Slogger, ctx context.Context) slog.Slogger {
+	if lg == nil {
+		return slog.FromContext(ctx)
+	}
+	return lg
+}
+
+func peerAddr(p *peer.Peer) string {
+	if p == nil || p.Addr == nil {
+		return ""
+	}
+	return p.Addr.String()
+}
+
+// logCall writes one access entry for a finished call.
+func logCall(lg slog.Slogger, method string, start time.Time, addr string, err error) {
+	code := status.Code(err)
+	lg = lg.WithFields(slog.Fields{
+		"method":   method,
+		"code":     code.String(),
+		"duration": time.Since(start).Seconds(),
+		"peer":     addr,
+	})
+	msg := fmt.Sprintf("%s %s", method, code)
+	switch codeLevel(code) {
+	case slog.InfoLevel:
+		lg.Info(msg)
+	case slog.WarnLevel:
+		lg.WithError(err).Warn(msg)
+	default:
+		lg.WithError(err).Error(msg)
+	}
+}
+
+// UnaryServerInterceptor logs each call through lg, or the Slogger in the
+// call's context if lg is nil. Handlers can log through
+// slog.FromContext(ctx), which carries the method.
+func UnaryServerInterceptor(lg slog.Slogger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		callLg := baseLogger(lg, ctx).WithFields(slog.Fields{"method": info.FullMethod})
+		p, _ := peer.FromContext(ctx)
+		resp, err := handler(slog.NewContext(ctx, callLg), req)
+		logCall(callLg, info.FullMethod, start, peerAddr(p), err)
+		return resp, err
+	}
+}
+
+// serverStream overrides the context of a stream to carry a Slogger.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (ss *serverStream) Context() context.Context {
+	return ss.ctx
+}
+
+// StreamServerInterceptor logs each stream when its handler returns.
+func StreamServerInterceptor(lg slog.Slogger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		ctx := ss.Context()
+		callLg := baseLogger(lg, ctx).WithFields(slog.Fields{"method": info.FullMethod})
+		p, _ := peer.FromContext(ctx)
+		err := handler(srv, &serverStream{ss, slog.NewContext(ctx, callLg)})
+		logCall(callLg, info.FullMethod, start, peerAddr(p), err)
+		return err
+	}
+}
+
+// UnaryClientInterceptor logs each outgoing call.
+func UnaryClientInterceptor(lg slog.Slogger) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		p := &peer.Peer{}
+		err := invoker(ctx, method, req, reply, cc, append(opts, grpc.Peer(p))...)
+		logCall(baseLogger(lg, ctx), method, start, peerAddr(p), err)
+		return err
+	}
+}
+
+// clientStream logs once, when the stream ends with an error or EOF.
+type clientStream struct {
+	grpc.ClientStream
+	once   sync.Once
+	lg     slog.Slogger
+	method string
+	start  time.Time
+	peer   *peer.Peer
+}
+
+func (cs *clientStream) finish(err error) {
+	if err == io.EOF {
+		err = nil
+	}
+	cs.once.Do(func() {
+		logCall(cs.lg, cs.method, cs.start, peerAddr(cs.peer), err)
+	})
+}
+
+func (cs *clientStream) RecvMsg(m interface{}) error {
+	err := cs.ClientStream.RecvMsg(m)
+	if err != nil {
+		cs.finish(err)
+	}
+	return err
+}
+
+// StreamClientInterceptor logs each outgoing stream once it has been read
+// to the end or fails. Streams abandoned before then are not logged.
+func StreamClientInterceptor(lg slog.Slogger) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		start := time.Now()
+		p := &peer.Peer{}
+		lg := baseLogger(lg, ctx)
+		stream, err := streamer(ctx, desc, cc, method, append(opts, grpc.Peer(p))...)
+		if err != nil {
+			logCall(lg, method, start, peerAddr(p), err)
+			return nil, err
+		}
+		return &clientStream{ClientStream: stream, lg: lg, method: method, start: start, peer: p}, nil
+	}
+}
+
+// Allow override for testing.
+var exit = os.Exit
+
+type loggerV2 struct {
+	lg        slog.Slogger
+	verbosity int
+}
+
+// NewLoggerV2 returns a grpclog.LoggerV2 that writes through lg. Fatal
+// messages are logged at FatalLevel before exiting. Install it with
+// grpclog.SetLoggerV2.
+func NewLoggerV2(lg slog.Slogger, verbosity int) grpcLog.LoggerV2 {
+	return &loggerV2{lg, verbosity}
+}
+
+// caller returns the source of gRPC's logging call, skipping this file and
+// grpclog's own wrappers.
+func (l *loggerV2) caller() slog.Slogger {
+	for depth := 2; ; depth++ {
+		_, file, line, ok := runtime.Caller(depth)
+		if !ok {
+			return l.lg
+		}
+		if strings.HasSuffix(file, "/grpclog/grpclog.go") || strings.HasSuffix(file, "/grpclog/logger.go") {
+			continue
+		}
+		if slash := strings.LastIndex(file, "/"); slash >= 0 {
+			file = file[slash+1:]
+		}
+		return l.lg.WithSource(fmt.Sprintf("%s:%d", file, line))
+	}
+}
+
+func (l *loggerV2) Info(args ...interface{}) {
+	l.caller().Info(args...)
+}
+
+func (l *loggerV2) Infoln(args ...interface{}) {
+	l.caller().Info(sprintln(args...))
+}
+
+func (l *loggerV2) Infof(format string, args ...interface{}) {
+	l.caller().Infof(format, args...)
+}
+
+func (l *loggerV2) Warning(args ...interface{}) {
+	l.caller().Warn(args...)
+}
+
+func (l *loggerV2) Warningln(args ...interface{}) {
+	l.caller().Warn(sprintln(args...))
+}
+
+func (l *loggerV2) Warningf(format string, args ...interface{}) {
+	l.caller().Warnf(format, args...)
+}
+
+func (l *loggerV2) Error(args ...interface{}) {
+	l.caller().Error(args...)
+}
+
+func (l *loggerV2) Errorln(args ...interface{}) {
+	l.caller().Error(sprintln(args...))
+}
+
+func (l *loggerV2) Errorf(format string, args ...interface{}) {
+	l.caller().Errorf(format, args...)
+}
+
+func (l *loggerV2) Fatal(args ...interface{}) {
+	l.caller().Log(slog.FatalLevel, args...)
+	exit(1)
+}
+
+func (l *loggerV2) Fatalln(args ...interface{}) {
+	l.caller().Log(slog.FatalLevel, sprintln(args...))
+	exit(1)
+}
+
+func (l *loggerV2) Fatalf(format string, args ...interface{}) {
+	l.caller().Logf(slog.FatalLevel, format, args...)
+	exit(1)
+}
+
+func (l *loggerV2) V(level int) bool {
+	return level <= l.verbosity
+}
+
+func sprintln(args ...interface{}) string {
+	return strings.TrimSuffix(fmt.Sprintln(args...), "\n")
+}
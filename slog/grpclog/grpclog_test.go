@@ -0,0 +1,168 @@
+package grpclog
+
+import (
+	"context"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/msolo/go-bis/slog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type entryLog struct {
+	mu      sync.Mutex
+	entries []slog.Entry
+}
+
+func (el *entryLog) WriteEntry(e slog.Entry) error {
+	el.mu.Lock()
+	defer el.mu.Unlock()
+	el.entries = append(el.entries, e)
+	return nil
+}
+
+// find waits briefly for an entry with the given message.
+func (el *entryLog) find(msg string) slog.Entry {
+	for i := 0; i < 100; i++ {
+		el.mu.Lock()
+		for _, e := range el.entries {
+			if e.Message() == msg {
+				el.mu.Unlock()
+				return e
+			}
+		}
+		el.mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+	}
+	return nil
+}
+
+func TestInterceptors(t *testing.T) {
+	el := &entryLog{}
+	saved := slog.GetHandler()
+	slog.SetHandler(el)
+	defer slog.SetHandler(saved)
+
+	lis := bufconn.Listen(1 << 16)
+	srv := grpc.NewServer(
+		grpc.UnaryInterceptor(UnaryServerInterceptor(nil)),
+		grpc.StreamInterceptor(StreamServerInterceptor(nil)))
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	cc, err := grpc.Dial("bufnet", grpc.WithInsecure(),
+		grpc.WithDialer(func(string, time.Duration) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithUnaryInterceptor(UnaryClientInterceptor(nil)),
+		grpc.WithStreamInterceptor(StreamClientInterceptor(nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cc.Close()
+	client := healthpb.NewHealthClient(cc)
+
+	ctx := context.Background()
+	if _, err := client.Check(ctx, &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: "missing"}); err == nil {
+		t.Fatal("expected NotFound")
+	}
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	stream, err := client.Watch(watchCtx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if _, err := stream.Recv(); err == nil {
+		t.Fatal("expected canceled stream")
+	}
+
+	checks := []struct {
+		msg   string
+		level slog.Level
+	}{
+		{"/grpc.health.v1.Health/Check OK", slog.InfoLevel},
+		{"/grpc.health.v1.Health/Check NotFound", slog.WarnLevel},
+		{"/grpc.health.v1.Health/Watch Canceled", slog.WarnLevel},
+	}
+	for _, c := range checks {
+		e := el.find(c.msg)
+		if e == nil {
+			t.Errorf("no entry %q", c.msg)
+			continue
+		}
+		if e.Level() != c.level {
+			t.Errorf("%q: level %v", c.msg, e.Level())
+		}
+		f := e.Fields()
+		if f["code"] == nil || f["duration"] == nil || f["peer"] == nil {
+			t.Errorf("%q: missing fields %v", c.msg, f)
+		}
+	}
+
+	// Each call is logged by both the client and the server, though the
+	// server may not have noticed the canceled stream yet.
+	n := 0
+	for i := 0; i < 100 && n < 6; i++ {
+		el.mu.Lock()
+		n = len(el.entries)
+		el.mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n != 6 {
+		t.Errorf("want 6 entries, got %d", n)
+	}
+}
+
+func TestLoggerV2(t *testing.T) {
+	el := &entryLog{}
+	saved := slog.GetHandler()
+	slog.SetHandler(el)
+	defer slog.SetHandler(saved)
+
+	exited := 0
+	exit = func(int) { exited++ }
+	defer func() { exit = os.Exit }()
+
+	lg := NewLoggerV2(slog.FromContext(context.Background()), 1)
+	lg.Infoln("a", "b")
+	lg.Warningf("w%d", 1)
+	lg.Fatal("boom")
+	if exited != 1 {
+		t.Error("Fatal did not exit")
+	}
+	if !lg.V(1) || lg.V(2) {
+		t.Error("bad verbosity")
+	}
+	want := []struct {
+		level slog.Level
+		msg   string
+	}{
+		{slog.InfoLevel, "a b"},
+		{slog.WarnLevel, "w1"},
+		{slog.FatalLevel, "boom"},
+	}
+	if len(el.entries) != len(want) {
+		t.Fatalf("expected %d entries, got %d", len(want), len(el.entries))
+	}
+	for i, e := range el.entries {
+		if e.Level() != want[i].level || e.Message() != want[i].msg {
+			t.Errorf("entry %d: %v %q", i, e.Level(), e.Message())
+		}
+		if !strings.HasPrefix(e.Source(), "grpclog_test.go:") {
+			t.Errorf("bad source %s", e.Source())
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package slog
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const DefaultRequestIDHeader = "X-Request-Id"
+
+type HTTPOptions struct {
+	// Handler, if set, also receives each access entry, in addition to
+	// the request's Slogger; for instance NewHandler(wr, ApacheFmtEntry)
+	// for a combined log.
+	Handler Handler
+	// Header carrying the request id; defaults to DefaultRequestIDHeader.
+	// Requests without one are assigned a random id, which is echoed in
+	// the response.
+	RequestIDHeader string
+}
+
+// statusWriter records the status and size of a response.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (sw *statusWriter) WriteHeader(status int) {
+	if sw.status == 0 {
+		sw.status = status
+	}
+	sw.ResponseWriter.WriteHeader(status)
+}
+
+func (sw *statusWriter) Write(b []byte) (int, error) {
+	if sw.status == 0 {
+		sw.status = http.StatusOK
+	}
+	n, err := sw.ResponseWriter.Write(b)
+	sw.bytes += int64(n)
+	return n, err
+}
+
+func (sw *statusWriter) flush() {
+	sw.ResponseWriter.(http.Flusher).Flush()
+}
+
+func (sw *statusWriter) hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := sw.ResponseWriter.(http.Hijacker).Hijack()
+	if err == nil && sw.status == 0 {
+		// The handler owns the connection now; nothing more can be sent
+		// through the ResponseWriter.
+		sw.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+func (sw *statusWriter) push(target string, opts *http.PushOptions) error {
+	return sw.ResponseWriter.(http.Pusher).Push(target, opts)
+}
+
+type flushFunc func()
+
+func (f flushFunc) Flush() { f() }
+
+type hijackFunc func() (net.Conn, *bufio.ReadWriter, error)
+
+func (f hijackFunc) Hijack() (net.Conn, *bufio.ReadWriter, error) { return f() }
+
+type pushFunc func(string, *http.PushOptions) error
+
+func (f pushFunc) Push(target string, opts *http.PushOptions) error { return f(target, opts) }
+
+// wrap returns sw as a ResponseWriter implementing exactly the optional
+// interfaces of the one it wraps, so handlers that check for them with a
+// type assertion see the same answer as without the middleware.
+func (sw *statusWriter) wrap() http.ResponseWriter {
+	_, isFlusher := sw.ResponseWriter.(http.Flusher)
+	_, isHijacker := sw.ResponseWriter.(http.Hijacker)
+	_, isPusher := sw.ResponseWriter.(http.Pusher)
+	f, h, p := flushFunc(sw.flush), hijackFunc(sw.hijack), pushFunc(sw.push)
+	switch {
+	case isFlusher && isHijacker && isPusher:
+		return struct {
+			*statusWriter
+			http.Flusher
+			http.Hijacker
+			http.Pusher
+		}{sw, f, h, p}
+	case isFlusher && isHijacker:
+		return struct {
+			*statusWriter
+			http.Flusher
+			http.Hijacker
+		}{sw, f, h}
+	case isFlusher && isPusher:
+		return struct {
+			*statusWriter
+			http.Flusher
+			http.Pusher
+		}{sw, f, p}
+	case isHijacker && isPusher:
+		return struct {
+			*statusWriter
+			http.Hijacker
+			http.Pusher
+		}{sw, h, p}
+	case isFlusher:
+		return struct {
+			*statusWriter
+			http.Flusher
+		}{sw, f}
+	case isHijacker:
+		return struct {
+			*statusWriter
+			http.Hijacker
+		}{sw, h}
+	case isPusher:
+		return struct {
+			*statusWriter
+			http.Pusher
+		}{sw, p}
+	}
+	return sw
+}
+
+func newRequestID() string {
+	var b [8]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+func statusLevel(status int) Level {
+	switch {
+	case status >= 500:
+		return ErrorLevel
+	case status >= 400:
+		return WarnLevel
+	}
+	return InfoLevel
+}
+
+// HTTPMiddleware logs one access entry per request, at InfoLevel, or
+// WarnLevel and ErrorLevel for 4xx and 5xx responses. Handlers can log
+// through FromContext(r.Context()), which carries the request id, method
+// and path.
+func HTTPMiddleware(next http.Handler, opts HTTPOptions) http.Handler {
+	header := opts.RequestIDHeader
+	if header == "" {
+		header = DefaultRequestIDHeader
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		id := r.Header.Get(header)
+		if id == "" {
+			id = newRequestID()
+			w.Header().Set(header, id)
+		}
+		reqFields := Fields{
+			"request_id": id,
+			"method":     r.Method,
+			"path":       r.URL.Path,
+		}
+		lg := FromContext(r.Context()).WithFields(reqFields)
+		sw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw.wrap(), r.WithContext(NewContext(r.Context(), lg)))
+
+		if sw.status == 0 {
+			sw.status = http.StatusOK
+		}
+		uri := r.RequestURI
+		if uri == "" {
+			uri = r.URL.RequestURI()
+		}
+		accessFields := Fields{
+			"uri":         uri,
+			"status":      sw.status,
+			"bytes":       sw.bytes,
+			"duration":    time.Since(start).Seconds(),
+			"remote_addr": r.RemoteAddr,
+			"user_agent":  r.UserAgent(),
+			"referer":     r.Referer(),
+			"proto":       r.Proto,
+		}
+		level := statusLevel(sw.status)
+		msg := fmt.Sprintf("%s %s %d", r.Method, r.URL.Path, sw.status)
+		logAt(lg.WithFields(accessFields), level, msg)
+		if opts.Handler != nil {
+			access := &entrySlogger{entry{fields: reqFields}, opts.Handler}
+			logAt(access.WithFields(accessFields), level, msg)
+		}
+	})
+}
+
+func apacheField(f Fields, key string) string {
+	v, ok := f[key]
+	if !ok {
+		return "-"
+	}
+	s := fmt.Sprint(v)
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// ApacheFmtEntry formats access entries from HTTPMiddleware in the Apache
+// combined log format, with the request URI as sent, query included.
+func ApacheFmtEntry(e Entry) string {
+	f := e.Fields()
+	host := apacheField(f, "remote_addr")
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	bytes := apacheField(f, "bytes")
+	if bytes == "0" {
+		bytes = "-"
+	}
+	uri := apacheField(f, "uri")
+	if uri == "-" {
+		uri = apacheField(f, "path")
+	}
+	quote := func(s string) string {
+		return strings.Replace(s, `"`, `\"`, -1)
+	}
+	return fmt.Sprintf("%s - - [%s] \"%s %s %s\" %s %s \"%s\" \"%s\"\n",
+		host,
+		e.Timestamp().Format("02/Jan/2006:15:04:05 -0700"),
+		apacheField(f, "method"),
+		quote(uri),
+		apacheField(f, "proto"),
+		apacheField(f, "status"),
+		bytes,
+		quote(apacheField(f, "referer")),
+		quote(apacheField(f, "user_agent")))
+}
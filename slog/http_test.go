@@ -0,0 +1,128 @@
+package slog
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTPMiddleware(t *testing.T) {
+	lg, lw := testSlog()
+	h := HTTPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		FromContext(r.Context()).Info("handling")
+		http.Error(w, "missing", http.StatusNotFound)
+	}), HTTPOptions{})
+
+	req := httptest.NewRequest("GET", "/widgets", nil)
+	req.Header.Set("User-Agent", "test/1.0")
+	req = req.WithContext(NewContext(req.Context(), lg))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	id := rec.Header().Get(DefaultRequestIDHeader)
+	if id == "" {
+		t.Fatal("no request id in response")
+	}
+	lines := lw.lines
+	if len(lines) != 2 {
+		t.Fatalf("want 2 lines, got %q", lines)
+	}
+	if !strings.Contains(lines[0], `"request_id":"`+id+`"`) {
+		t.Errorf("request logger missing id: %s", lines[0])
+	}
+	access := lines[1]
+	if !strings.HasPrefix(access, "W") {
+		t.Errorf("4xx not logged as warning: %s", access)
+	}
+	for _, want := range []string{"GET /widgets 404", `"status":404`, `"bytes":8`,
+		`"user_agent":"test/1.0"`, `"remote_addr":"192.0.2.1:1234"`, `"duration":`} {
+		if !strings.Contains(access, want) {
+			t.Errorf("missing %s: %s", want, access)
+		}
+	}
+}
+
+func TestHTTPMiddlewareApache(t *testing.T) {
+	lg, slw := testSlog()
+	lw := &lineWriter{}
+	h := HTTPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	}), HTTPOptions{Handler: NewHandler(lw, ApacheFmtEntry)})
+
+	req := httptest.NewRequest("GET", "/search?q=a+b", nil)
+	req = req.WithContext(NewContext(req.Context(), lg))
+	req.Header.Set(DefaultRequestIDHeader, "abc")
+	req.Header.Set("Referer", "http://example.com/")
+	req.Header.Set("User-Agent", "test/1.0")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	lastLine, _ := lw.LastLine()
+	lastLine = strings.TrimSpace(lastLine)
+	if !strings.HasPrefix(lastLine, "192.0.2.1 - - [") ||
+		!strings.HasSuffix(lastLine, `] "GET /search?q=a+b HTTP/1.1" 200 5 "http://example.com/" "test/1.0"`) {
+		t.Errorf("bad combined log line: %s", lastLine)
+	}
+	// The structured entry is still written to the request's logger.
+	access, _ := slw.LastLine()
+	for _, want := range []string{"GET /search 200", `"request_id":"abc"`, `"uri":"/search?q=a+b"`} {
+		if !strings.Contains(access, want) {
+			t.Errorf("missing %s: %s", want, access)
+		}
+	}
+}
+
+// hijackRecorder supports the optional ResponseWriter interfaces.
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+	hijacked bool
+	pushed   string
+}
+
+func (hr *hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hr.hijacked = true
+	return nil, nil, nil
+}
+
+func (hr *hijackRecorder) Push(target string, opts *http.PushOptions) error {
+	hr.pushed = target
+	return nil
+}
+
+func TestHTTPMiddlewareHijack(t *testing.T) {
+	lg, lw := testSlog()
+	h := HTTPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := w.(http.Pusher).Push("/style.css", nil); err != nil {
+			t.Error(err)
+		}
+		if _, _, err := w.(http.Hijacker).Hijack(); err != nil {
+			t.Error(err)
+		}
+	}), HTTPOptions{})
+	req := httptest.NewRequest("GET", "/ws", nil)
+	req = req.WithContext(NewContext(req.Context(), lg))
+	rec := &hijackRecorder{ResponseRecorder: httptest.NewRecorder()}
+	h.ServeHTTP(rec, req)
+	if !rec.hijacked || rec.pushed != "/style.css" {
+		t.Errorf("not passed through: hijacked %v, pushed %q", rec.hijacked, rec.pushed)
+	}
+	if access, _ := lw.LastLine(); !strings.Contains(access, `"status":101`) {
+		t.Errorf("hijacked request not logged as 101: %s", access)
+	}
+
+	// A ResponseWriter without the interfaces does not gain them.
+	h = HTTPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := w.(http.Flusher); !ok {
+			t.Error("Flusher hidden")
+		}
+		if _, ok := w.(http.Hijacker); ok {
+			t.Error("Hijacker added")
+		}
+		if _, ok := w.(http.Pusher); ok {
+			t.Error("Pusher added")
+		}
+	}), HTTPOptions{})
+	h.ServeHTTP(httptest.NewRecorder(), req)
+}
@@ -52,6 +52,13 @@ type Slogger interface {
 	WithFielder(f Fielder) Slogger
 	WithError(err error) Slogger
 	WithSource(src string) Slogger
+	// Named returns a child logger; see LoggerField and NameLevels.
+	Named(name string) Slogger
+	// Enabled reports whether entries at level might be written.
+	Enabled(level Level) bool
+	// Log and Logf write at any level, including custom ones.
+	Log(level Level, args ...interface{})
+	Logf(level Level, format string, args ...interface{})
 	Logger
 }
 
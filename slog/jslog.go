@@ -39,6 +39,19 @@ func (mh *multiHandler) WriteEntry(e Entry) error {
 	return nil
 }
 
+func (mh *multiHandler) Enabled(level Level, logger string) bool {
+	for _, h := range mh.handlers {
+		if handlerEnabled(h, level, logger) {
+			return true
+		}
+	}
+	return false
+}
+
+func (mh *multiHandler) wrappedHandlers() []Handler {
+	return mh.handlers
+}
+
 func NewMultiHandler(handlers ...Handler) Handler {
 	return &multiHandler{handlers: handlers}
 }
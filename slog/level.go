@@ -2,48 +2,202 @@ package slog
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 )
 
+// Built-in levels are spaced apart so custom levels can be registered
+// between them; see RegisterLevel.
 const (
-	InvalidLevel Level = iota - 1
-	DebugLevel
-	InfoLevel
-	WarnLevel
-	ErrorLevel
-	FatalLevel
-	MaxLevels
+	InvalidLevel Level = -1
+	DebugLevel   Level = 0
+	InfoLevel    Level = 4
+	WarnLevel    Level = 8
+	ErrorLevel   Level = 12
+	FatalLevel   Level = 16
+	// MaxLevels is one past the highest built-in level.
+	MaxLevels = FatalLevel + 1
 )
 
-var levelChar = [MaxLevels]byte{
-	'D',
-	'I',
-	'W',
-	'E',
-	'F',
+// Registered levels must fit in a signed byte, as the Store index holds
+// them in one.
+const (
+	minRegisteredLevel Level = -128
+	maxRegisteredLevel Level = 127
+)
+
+// legacyLevel converts a level written before levels were spaced apart,
+// when they ran from 0 for debug to 4 for fatal.
+func legacyLevel(n int64) Level {
+	if n < 0 || n > 4 {
+		return InvalidLevel
+	}
+	return Level(n * 4)
+}
+
+type levelInfo struct {
+	level Level
+	name  string
+	char  byte
+}
+
+// levelTable is replaced wholesale on registration so formatting can read
+// it without locking.
+type levelTable struct {
+	// Sorted by level.
+	infos   []*levelInfo
+	byLevel map[Level]*levelInfo
+	byName  map[string]Level
+	byChar  map[byte]Level
+}
+
+var (
+	levelMu sync.Mutex
+	levels  atomic.Value // *levelTable
+)
+
+func init() {
+	lt := &levelTable{
+		byLevel: make(map[Level]*levelInfo),
+		byName: map[string]Level{
+			"warning": WarnLevel,
+			"err":     ErrorLevel,
+			"crit":    FatalLevel,
+		},
+		byChar: make(map[byte]Level),
+	}
+	for _, li := range []*levelInfo{
+		{DebugLevel, "debug", 'D'},
+		{InfoLevel, "info", 'I'},
+		{WarnLevel, "warn", 'W'},
+		{ErrorLevel, "error", 'E'},
+		{FatalLevel, "fatal", 'F'},
+	} {
+		lt.add(li)
+	}
+	levels.Store(lt)
 }
 
-var levelName = [MaxLevels]string{
-	"debug",
-	"info",
-	"warn",
-	"error",
-	"fatal",
+func (lt *levelTable) add(li *levelInfo) {
+	i := 0
+	for i < len(lt.infos) && lt.infos[i].level < li.level {
+		i++
+	}
+	lt.infos = append(lt.infos, nil)
+	copy(lt.infos[i+1:], lt.infos[i:])
+	lt.infos[i] = li
+	lt.byLevel[li.level] = li
+	lt.byName[li.name] = li.level
+	lt.byChar[li.char] = li.level
 }
 
-var levelNameMap = map[string]Level{
-	"debug": DebugLevel,
-	"info":  InfoLevel,
-	"warn":  WarnLevel,
-	"error": ErrorLevel,
+func (lt *levelTable) clone() *levelTable {
+	nt := &levelTable{
+		infos:   append([]*levelInfo(nil), lt.infos...),
+		byLevel: make(map[Level]*levelInfo, len(lt.byLevel)),
+		byName:  make(map[string]Level, len(lt.byName)),
+		byChar:  make(map[byte]Level, len(lt.byChar)),
+	}
+	for k, v := range lt.byLevel {
+		nt.byLevel[k] = v
+	}
+	for k, v := range lt.byName {
+		nt.byName[k] = v
+	}
+	for k, v := range lt.byChar {
+		nt.byChar[k] = v
+	}
+	return nt
+}
+
+func levelTableNow() *levelTable {
+	return levels.Load().(*levelTable)
+}
+
+// RegisterLevel adds a custom level with a name, which is also accepted
+// by Set and UnmarshalText, and a character for the glog and console
+// formats. Levels must lie between -128 and 127. Register levels at init,
+// before logging starts.
+//
+//	const (
+//		TraceLevel  = slog.DebugLevel - 4
+//		NoticeLevel = slog.InfoLevel + 2
+//	)
+//
+//	func init() {
+//		slog.RegisterLevel(TraceLevel, "trace", 'T')
+//		slog.RegisterLevel(NoticeLevel, "notice", 'N')
+//	}
+func RegisterLevel(level Level, name string, char byte) error {
+	name = strings.ToLower(name)
+	if level == InvalidLevel {
+		return fmt.Errorf("slog: can't register InvalidLevel")
+	}
+	if level < minRegisteredLevel || level > maxRegisteredLevel {
+		return fmt.Errorf("slog: level %d outside %d..%d", level, minRegisteredLevel, maxRegisteredLevel)
+	}
+	if name == "" || strings.ContainsAny(name, " ,=()") {
+		return fmt.Errorf("slog: invalid level name: %q", name)
+	}
+	if char <= ' ' || char > '~' {
+		return fmt.Errorf("slog: invalid level character: %q", char)
+	}
+
+	levelMu.Lock()
+	defer levelMu.Unlock()
+	lt := levelTableNow()
+	if li, ok := lt.byLevel[level]; ok {
+		return fmt.Errorf("slog: level %d already registered as %s", level, li.name)
+	}
+	if _, ok := lt.byName[name]; ok {
+		return fmt.Errorf("slog: level name %s already registered", name)
+	}
+	if l, ok := lt.byChar[char]; ok {
+		return fmt.Errorf("slog: level character %c already registered for %s", char, l)
+	}
+	lt = lt.clone()
+	lt.add(&levelInfo{level, name, char})
+	levels.Store(lt)
+	return nil
+}
+
+// nearest returns the registered level at or below l, or the lowest one
+// if l is below them all, so unregistered levels still format sensibly.
+func (lt *levelTable) nearest(l Level) *levelInfo {
+	if li, ok := lt.byLevel[l]; ok {
+		return li
+	}
+	li := lt.infos[0]
+	for _, x := range lt.infos {
+		if x.level > l {
+			break
+		}
+		li = x
+	}
+	return li
+}
+
+// levelChar returns the single character used for l in glog and console
+// output.
+func levelChar(l Level) byte {
+	return levelTableNow().nearest(l).char
 }
 
 func parseLevel(val string) (Level, error) {
-	x, ok := levelNameMap[strings.ToLower(val)]
-	if !ok {
-		return InvalidLevel, fmt.Errorf("invalid log level: %s", val)
+	lt := levelTableNow()
+	val = strings.ToLower(val)
+	if x, ok := lt.byName[val]; ok {
+		return x, nil
+	}
+	// Accept what String returns for unregistered levels.
+	if strings.HasPrefix(val, "level(") && strings.HasSuffix(val, ")") {
+		if n, err := strconv.Atoi(val[6 : len(val)-1]); err == nil && Level(n) != InvalidLevel {
+			return Level(n), nil
+		}
 	}
-	return x, nil
+	return InvalidLevel, fmt.Errorf("invalid log level: %s", val)
 }
 
 func (l *Level) Set(val string) (err error) {
@@ -51,6 +205,21 @@ func (l *Level) Set(val string) (err error) {
 	return err
 }
 
-func (l *Level) String() string {
-	return levelName[int(*l)]
+// String returns the level's name, or level(n) if it isn't registered.
+func (l Level) String() string {
+	if li, ok := levelTableNow().byLevel[l]; ok {
+		return li.name
+	}
+	return fmt.Sprintf("level(%d)", int(l))
+}
+
+// MarshalText encodes the level as its name, so levels appear in JSON as
+// strings.
+func (l Level) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+// UnmarshalText accepts any name or alias that Set does.
+func (l *Level) UnmarshalText(text []byte) error {
+	return l.Set(string(text))
 }
@@ -0,0 +1,116 @@
+package slog
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const (
+	testTraceLevel  = DebugLevel - 4
+	testNoticeLevel = InfoLevel + 2
+)
+
+func init() {
+	if err := RegisterLevel(testTraceLevel, "trace", 'T'); err != nil {
+		panic(err)
+	}
+	if err := RegisterLevel(testNoticeLevel, "Notice", 'N'); err != nil {
+		panic(err)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for name, want := range map[string]Level{
+		"debug":     DebugLevel,
+		"INFO":      InfoLevel,
+		"warning":   WarnLevel,
+		"err":       ErrorLevel,
+		"fatal":     FatalLevel,
+		"crit":      FatalLevel,
+		"trace":     testTraceLevel,
+		"notice":    testNoticeLevel,
+		"level(10)": Level(10),
+	} {
+		var l Level
+		if err := l.Set(name); err != nil || l != want {
+			t.Errorf("%s: got %v, %v, want %v", name, l, err, want)
+		}
+	}
+	var l Level
+	if err := l.Set("loud"); err == nil {
+		t.Error("expected error for unknown level")
+	}
+	if s := Level(10).String(); s != "level(10)" {
+		t.Errorf("unregistered level: got %q", s)
+	}
+	if c := levelChar(Level(10)); c != 'W' {
+		t.Errorf("unregistered level char: got %c, want W", c)
+	}
+}
+
+func TestRegisterLevelConflicts(t *testing.T) {
+	for _, tc := range []struct {
+		level Level
+		name  string
+		char  byte
+	}{
+		{InfoLevel, "chatty", 'C'},
+		{Level(1), "info", 'C'},
+		{Level(1), "chatty", 'I'},
+		{InvalidLevel, "chatty", 'C'},
+		{Level(1), "", 'C'},
+		{Level(128), "huge", 'H'},
+		{Level(-129), "tiny", 't'},
+	} {
+		if err := RegisterLevel(tc.level, tc.name, tc.char); err == nil {
+			t.Errorf("RegisterLevel(%d, %q, %c) succeeded", tc.level, tc.name, tc.char)
+		}
+	}
+}
+
+func TestLevelJSON(t *testing.T) {
+	data, err := json.Marshal(map[string]Level{"a": testNoticeLevel, "b": ErrorLevel})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"a":"notice","b":"error"}` {
+		t.Errorf("got %s", data)
+	}
+	m := map[string]Level{}
+	if err := json.Unmarshal(data, &m); err != nil || m["a"] != testNoticeLevel || m["b"] != ErrorLevel {
+		t.Errorf("round trip: %v, %v", m, err)
+	}
+
+	// Entries written before levels were names, or spaced apart.
+	e, err := ParseJsonEntry([]byte(`{"Level":3,"Message":"old"}`))
+	if err != nil || e.Level() != ErrorLevel {
+		t.Errorf("legacy level: got %v, %v", e, err)
+	}
+}
+
+func TestCustomLevelLogging(t *testing.T) {
+	slog, lw := testSlog()
+	slog.cfg.Level = testTraceLevel
+	slog.WithSource("trace.go:1").Logf(testNoticeLevel, "%d new mail", 3)
+	line, _ := lw.LastLine()
+	if !strings.HasPrefix(line, "N") || !strings.Contains(line, "3 new mail") {
+		t.Fatalf("unexpected line: %q", line)
+	}
+	e, err := ParseGlogEntry([]byte(line))
+	if err != nil || e.Level() != testNoticeLevel {
+		t.Errorf("glog round trip: %v, %v", e, err)
+	}
+	if sev := gcpSeverity(testNoticeLevel); sev != "NOTICE" {
+		t.Errorf("gcp severity: got %s", sev)
+	}
+	if n := otelSeverityNumber(testTraceLevel); n != 1 {
+		t.Errorf("otel severity: got %d", n)
+	}
+
+	slog.cfg.Level = DebugLevel
+	slog.Log(testTraceLevel, "hidden")
+	if line2, _ := lw.LastLine(); line2 != line {
+		t.Errorf("trace entry written below the configured level: %q", line2)
+	}
+}
@@ -0,0 +1,199 @@
+package slog
+
+import (
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+type metricKey struct {
+	level  Level
+	source string
+}
+
+// Metrics counts log volume. Entries are counted by level and source file
+// by a handler from NewMetricsHandler, bytes by level by a formatter from
+// FmtEntry. Setting Config.Metrics wires up both for Setup. Metrics is
+// also an http.Handler serving the Prometheus text exposition format.
+type Metrics struct {
+	mu       sync.Mutex
+	entries  map[metricKey]uint64
+	bytes    map[Level]uint64
+	errors   uint64
+	droppers []dropCounter
+}
+
+// dropCounter is implemented by handlers that discard entries, such as
+// AsyncHandler.
+type dropCounter interface {
+	Dropped() uint64
+}
+
+// handlerWrapper is implemented by handlers that pass entries on to
+// others, so NewMetricsHandler can find an AsyncHandler anywhere in a tree.
+type handlerWrapper interface {
+	wrappedHandlers() []Handler
+}
+
+func findDropCounters(h Handler) []dropCounter {
+	var dcs []dropCounter
+	if dc, ok := h.(dropCounter); ok {
+		dcs = append(dcs, dc)
+	}
+	if hw, ok := h.(handlerWrapper); ok {
+		for _, wh := range hw.wrappedHandlers() {
+			dcs = append(dcs, findDropCounters(wh)...)
+		}
+	}
+	return dcs
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{
+		entries: make(map[metricKey]uint64),
+		bytes:   make(map[Level]uint64),
+	}
+}
+
+// sourceFile trims the line number so metrics don't explode in
+// cardinality.
+func sourceFile(src string) string {
+	if i := strings.LastIndexByte(src, ':'); i >= 0 {
+		return src[:i]
+	}
+	return src
+}
+
+type metricsHandler struct {
+	h Handler
+	m *Metrics
+}
+
+func (mh *metricsHandler) Enabled(level Level, logger string) bool {
+	return handlerEnabled(mh.h, level, logger)
+}
+
+func (mh *metricsHandler) wrappedHandlers() []Handler {
+	return []Handler{mh.h}
+}
+
+func (mh *metricsHandler) WriteEntry(e Entry) error {
+	err := mh.h.WriteEntry(e)
+	key := metricKey{e.Level(), sourceFile(e.Source())}
+	mh.m.mu.Lock()
+	mh.m.entries[key]++
+	mh.m.mu.Unlock()
+	if err != nil {
+		atomic.AddUint64(&mh.m.errors, 1)
+	}
+	return err
+}
+
+// NewMetricsHandler counts entries and write errors passing through to h.
+// Drops by any AsyncHandler in the tree under h are reported too, as long
+// as the handlers above it are from this package; Middleware hides what
+// it wraps.
+func NewMetricsHandler(h Handler, m *Metrics) Handler {
+	if dcs := findDropCounters(h); len(dcs) > 0 {
+		m.mu.Lock()
+		m.droppers = append(m.droppers, dcs...)
+		m.mu.Unlock()
+	}
+	return &metricsHandler{h: h, m: m}
+}
+
+// FmtEntry wraps fmtEntry to count the formatted bytes by level.
+func (m *Metrics) FmtEntry(fmtEntry FmtEntry) FmtEntry {
+	return func(e Entry) string {
+		data := fmtEntry(e)
+		m.mu.Lock()
+		m.bytes[e.Level()] += uint64(len(data))
+		m.mu.Unlock()
+		return data
+	}
+}
+
+func (m *Metrics) dropped() uint64 {
+	var n uint64
+	for _, dc := range m.droppers {
+		n += dc.Dropped()
+	}
+	return n
+}
+
+// Snapshot returns the current counts in a form suitable for JSON.
+func (m *Metrics) Snapshot() map[string]interface{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entries := make(map[string]map[string]uint64)
+	for k, n := range m.entries {
+		name := k.level.String()
+		if entries[name] == nil {
+			entries[name] = make(map[string]uint64)
+		}
+		entries[name][k.source] = n
+	}
+	bytes := make(map[string]uint64, len(m.bytes))
+	for l, n := range m.bytes {
+		bytes[l.String()] = n
+	}
+	return map[string]interface{}{
+		"Entries":     entries,
+		"Bytes":       bytes,
+		"WriteErrors": atomic.LoadUint64(&m.errors),
+		"Dropped":     m.dropped(),
+	}
+}
+
+// Publish exports the metrics through expvar under name.
+func (m *Metrics) Publish(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return m.Snapshot()
+	}))
+}
+
+var promLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// WritePrometheus writes the metrics in Prometheus text exposition format.
+func (m *Metrics) WritePrometheus(wr io.Writer) error {
+	m.mu.Lock()
+	entries := make([]string, 0, len(m.entries))
+	for k, n := range m.entries {
+		entries = append(entries, fmt.Sprintf("slog_entries_total{level=%q,source=\"%s\"} %d\n",
+			k.level.String(), promLabelEscaper.Replace(k.source), n))
+	}
+	bytes := make([]string, 0, len(m.bytes))
+	for l, n := range m.bytes {
+		bytes = append(bytes, fmt.Sprintf("slog_bytes_total{level=%q} %d\n", l.String(), n))
+	}
+	dropped := m.dropped()
+	m.mu.Unlock()
+	sort.Strings(entries)
+	sort.Strings(bytes)
+
+	b := &strings.Builder{}
+	b.WriteString("# HELP slog_entries_total Log entries written by level and source file.\n")
+	b.WriteString("# TYPE slog_entries_total counter\n")
+	b.WriteString(strings.Join(entries, ""))
+	b.WriteString("# HELP slog_bytes_total Formatted log bytes by level.\n")
+	b.WriteString("# TYPE slog_bytes_total counter\n")
+	b.WriteString(strings.Join(bytes, ""))
+	b.WriteString("# HELP slog_write_errors_total Handler write errors.\n")
+	b.WriteString("# TYPE slog_write_errors_total counter\n")
+	fmt.Fprintf(b, "slog_write_errors_total %d\n", atomic.LoadUint64(&m.errors))
+	b.WriteString("# HELP slog_dropped_total Log entries discarded before being written.\n")
+	b.WriteString("# TYPE slog_dropped_total counter\n")
+	fmt.Fprintf(b, "slog_dropped_total %d\n", dropped)
+	_, err := io.WriteString(wr, b.String())
+	return err
+}
+
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WritePrometheus(w)
+}
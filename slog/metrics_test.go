@@ -0,0 +1,96 @@
+package slog
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type failHandler struct{}
+
+func (failHandler) WriteEntry(e Entry) error {
+	return errors.New("disk full")
+}
+
+func TestMetricsHandler(t *testing.T) {
+	m := NewMetrics()
+	cfg := &Config{}
+	lw := &lineWriter{}
+	h := NewMetricsHandler(NewMultiHandler(
+		NewHandler(lw, m.FmtEntry(GlogFmtEntry)),
+		failHandler{},
+	), m)
+	slog := &slogger{h: NewLevelHandler(h, cfg), cfg: cfg}
+	slog.Info("one")
+	slog.Error("two")
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	data, _ := ioutil.ReadAll(rec.Body)
+	body := string(data)
+
+	for _, want := range []string{
+		`slog_entries_total{level="info",source="metrics_test.go"} 1`,
+		`slog_entries_total{level="error",source="metrics_test.go"} 1`,
+		"slog_write_errors_total 2",
+		"slog_dropped_total 0",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("missing %q in metrics:\n%s", want, body)
+		}
+	}
+	if !strings.Contains(body, fmt.Sprintf("slog_bytes_total{level=\"info\"} %d", len(lw.lines[0]))) {
+		t.Errorf("byte count wrong for %q:\n%s", lw.lines[0], body)
+	}
+}
+
+func TestConfigMetrics(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	fname := filepath.Join(tmpDir, "test.log")
+	m := NewMetrics()
+	cfg := &Config{Format: JsonFormat, Outputs: []string{fname}, AsyncQueue: 16, Metrics: m}
+	h, closer, err := NewConfigHandler(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.droppers) != 1 {
+		t.Fatalf("AsyncHandler in the config tree not found: %d drop counters", len(m.droppers))
+	}
+	slog := &slogger{h: h, cfg: cfg}
+	slog.Info("one")
+	slog.Info("two")
+	if err := closer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snap := m.Snapshot()
+	if n := snap["Bytes"].(map[string]uint64)["info"]; n != uint64(len(data)) {
+		t.Errorf("counted %d bytes, wrote %d", n, len(data))
+	}
+	if n := snap["Entries"].(map[string]map[string]uint64)["info"]["metrics_test.go"]; n != 2 {
+		t.Errorf("counted %d entries, want 2", n)
+	}
+
+	// Drop counters are found below other handlers too.
+	m = NewMetrics()
+	ah := NewAsyncHandler(failHandler{}, 1)
+	defer ah.Close()
+	NewMetricsHandler(NewLevelHandler(NewMultiHandler(ah), cfg), m)
+	if len(m.droppers) != 1 {
+		t.Errorf("nested AsyncHandler not found")
+	}
+}
@@ -0,0 +1,123 @@
+package slog
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+// Middleware wraps a Handler to inspect or rewrite entries on their way
+// to it.
+type Middleware func(Handler) Handler
+
+// HandlerFunc adapts a function to the Handler interface.
+type HandlerFunc func(e Entry) error
+
+func (hf HandlerFunc) WriteEntry(e Entry) error {
+	return hf(e)
+}
+
+// Chain wraps h in mws. The first middleware sees each entry first.
+func Chain(h Handler, mws ...Middleware) Handler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		h = mws[i](h)
+	}
+	return h
+}
+
+// MapFields returns a Middleware that replaces the fields of each entry
+// with the result of fn. fn receives a copy it may modify.
+func MapFields(fn func(e Entry, f Fields) Fields) Middleware {
+	return func(h Handler) Handler {
+		return HandlerFunc(func(e Entry) error {
+			f := fn(e, mergeFields(nil, e.Fields()))
+			return h.WriteEntry(&EntryView{Entry: e, FieldsFunc: func() Fields { return f }})
+		})
+	}
+}
+
+// StaticFields adds fixed fields, like service, version and env, to every
+// entry. Fields set on the entry take precedence.
+func StaticFields(static Fields) Middleware {
+	return MapFields(func(e Entry, f Fields) Fields {
+		return mergeFields(static, f)
+	})
+}
+
+// goid returns the id of the calling goroutine. This is for labeling logs,
+// never for program logic.
+func goid() int64 {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	// "goroutine 123 [running]: ..."
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i >= 0 {
+		b = b[:i]
+	}
+	id, _ := strconv.ParseInt(string(b), 10, 64)
+	return id
+}
+
+// GoroutineID adds the id of the logging goroutine as "goroutine". The
+// entry must be handled synchronously, so put this ahead of any
+// AsyncHandler.
+func GoroutineID() Middleware {
+	return MapFields(func(e Entry, f Fields) Fields {
+		f["goroutine"] = goid()
+		return f
+	})
+}
+
+// Elapsed adds the monotonic time since process start as "elapsed", in
+// seconds. Unlike timestamps, this is immune to clock adjustments.
+func Elapsed() Middleware {
+	return MapFields(func(e Entry, f Fields) Fields {
+		f["elapsed"] = time.Since(startTime).Seconds()
+		return f
+	})
+}
+
+// MemStats adds runtime memory statistics as "mem" to entries at
+// ErrorLevel and above. Reading them briefly stops the world, so they
+// are not collected for routine entries.
+func MemStats() Middleware {
+	return MapFields(func(e Entry, f Fields) Fields {
+		if e.Level() < ErrorLevel {
+			return f
+		}
+		ms := &runtime.MemStats{}
+		runtime.ReadMemStats(ms)
+		f["mem"] = Fields{
+			"heap_alloc": ms.HeapAlloc,
+			"heap_sys":   ms.HeapSys,
+			"sys":        ms.Sys,
+			"num_gc":     ms.NumGC,
+			"goroutines": runtime.NumGoroutine(),
+		}
+		return f
+	})
+}
+
+// RenameFields renames fields according to names, old to new.
+func RenameFields(names map[string]string) Middleware {
+	return MapFields(func(e Entry, f Fields) Fields {
+		for from, to := range names {
+			if v, ok := f[from]; ok {
+				delete(f, from)
+				f[to] = v
+			}
+		}
+		return f
+	})
+}
+
+// DropFields removes the named fields.
+func DropFields(names ...string) Middleware {
+	return MapFields(func(e Entry, f Fields) Fields {
+		for _, name := range names {
+			delete(f, name)
+		}
+		return f
+	})
+}
@@ -0,0 +1,42 @@
+package slog
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMiddlewareChain(t *testing.T) {
+	cfg := &Config{}
+	lw := &lineWriter{}
+	h := Chain(NewHandler(lw, JsonFmtEntry),
+		StaticFields(Fields{"service": "api", "env": "prod"}),
+		GoroutineID(),
+		MemStats(),
+		RenameFields(map[string]string{"user": "user_id"}),
+		DropFields("env"),
+	)
+	slog := &slogger{h: NewLevelHandler(h, cfg), cfg: cfg}
+
+	fields := Fields{"user": 42, "service": "override"}
+	slog.WithFields(fields).Info("enriched")
+	lastLine, _ := lw.LastLine()
+	for _, want := range []string{`"service":"override"`, `"user_id":42`, `"goroutine":`} {
+		if !strings.Contains(lastLine, want) {
+			t.Errorf("missing %s: %s", want, lastLine)
+		}
+	}
+	for _, unwanted := range []string{`"env"`, `"user"`, `"mem"`} {
+		if strings.Contains(lastLine, unwanted) {
+			t.Errorf("unexpected %s: %s", unwanted, lastLine)
+		}
+	}
+	if _, ok := fields["user_id"]; ok {
+		t.Error("caller fields mutated")
+	}
+
+	slog.Error("failed")
+	lastLine, _ = lw.LastLine()
+	if !strings.Contains(lastLine, `"heap_alloc":`) {
+		t.Errorf("missing memory stats on error: %s", lastLine)
+	}
+}
@@ -0,0 +1,78 @@
+package slog
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// LoggerField holds the dotted name of a logger created by Named.
+const LoggerField = "logger"
+
+func (lg *slogger) Named(name string) Slogger {
+	return (&entrySlogger{handler: lg}).Named(name)
+}
+
+// Named returns a child logger whose entries carry the parent's name and
+// name joined by a dot in LoggerField.
+func (esl *entrySlogger) Named(name string) Slogger {
+	if parent, ok := esl.Fields()[LoggerField].(string); ok && parent != "" {
+		name = parent + "." + name
+	}
+	return esl.WithFields(Fields{LoggerField: name})
+}
+
+// NameLevels sets the minimum level of named loggers by name prefix, as in
+// "db=debug,http=warn". A logger uses the level of the longest prefix
+// matching whole name components, so "db.pool" inherits the level of
+// "db", and loggers without a match use Config.Level.
+type NameLevels map[string]Level
+
+func (nl *NameLevels) Set(val string) error {
+	levels := make(NameLevels)
+	for _, spec := range strings.Split(val, ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		parts := strings.SplitN(spec, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return fmt.Errorf("invalid logger level: %s", spec)
+		}
+		level, err := parseLevel(parts[1])
+		if err != nil {
+			return err
+		}
+		levels[parts[0]] = level
+	}
+	*nl = levels
+	return nil
+}
+
+func (nl *NameLevels) String() string {
+	names := make([]string, 0, len(*nl))
+	for name := range *nl {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	specs := make([]string, len(names))
+	for i, name := range names {
+		level := (*nl)[name]
+		specs[i] = name + "=" + level.String()
+	}
+	return strings.Join(specs, ",")
+}
+
+// level returns the level configured for the logger name, if any.
+func (nl NameLevels) level(name string) (Level, bool) {
+	for {
+		if level, ok := nl[name]; ok {
+			return level, true
+		}
+		dot := strings.LastIndexByte(name, '.')
+		if dot < 0 {
+			return InvalidLevel, false
+		}
+		name = name[:dot]
+	}
+}
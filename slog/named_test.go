@@ -0,0 +1,42 @@
+package slog
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNamedLevels(t *testing.T) {
+	slog, lw := testSlog()
+	slog.cfg.Level = WarnLevel
+	if err := slog.cfg.Levels.Set("db=debug, db.pool=error,http=warn"); err != nil {
+		t.Fatal(err)
+	}
+	if got := slog.cfg.Levels.String(); got != "db=debug,db.pool=error,http=warn" {
+		t.Errorf("bad String: %s", got)
+	}
+
+	db := slog.Named("db")
+	db.Named("conn").Info("inherits debug")
+	lastLine, _ := lw.LastLine()
+	if !strings.Contains(lastLine, "inherits debug") || !strings.Contains(lastLine, `"logger":"db.conn"`) {
+		t.Errorf("db.conn entry missing: %q", lastLine)
+	}
+
+	n := len(lw.lines)
+	db.Named("pool").Warn("below pool level")
+	slog.Named("dbx").Info("not under db")
+	slog.Info("below default level")
+	if len(lw.lines) != n {
+		t.Errorf("entries not filtered: %q", lw.lines[n:])
+	}
+
+	slog.Named("http").WithFields(Fields{"path": "/"}).Warn("http warning")
+	lastLine, _ = lw.LastLine()
+	if !strings.Contains(lastLine, `"logger":"http"`) {
+		t.Errorf("http entry missing: %q", lastLine)
+	}
+
+	if err := slog.cfg.Levels.Set("db"); err == nil {
+		t.Error("accepted spec without level")
+	}
+}
@@ -0,0 +1,154 @@
+package slog
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Allow override for testing.
+var exit = os.Exit
+
+type RecoverOptions struct {
+	// Log at FatalLevel rather than ErrorLevel.
+	Fatal bool
+	// Panic again with the original value after logging.
+	Repanic bool
+	// Exit the process with status 2, as an unrecovered panic would, after
+	// logging. Takes precedence over Repanic.
+	Exit bool
+}
+
+// panicError carries a recovered value and the stack of the panic, so it
+// is reported through Entry.StackTrace like any other stack error.
+type panicError struct {
+	value interface{}
+	stack errors.StackTrace
+}
+
+func (pe *panicError) Error() string {
+	if err, ok := pe.value.(error); ok {
+		return err.Error()
+	}
+	return fmt.Sprint(pe.value)
+}
+
+func (pe *panicError) StackTrace() errors.StackTrace {
+	return pe.stack
+}
+
+func (pe *panicError) Cause() error {
+	if err, ok := pe.value.(error); ok {
+		return err
+	}
+	return nil
+}
+
+// panicStack returns the stack of the panicking goroutine, starting at the
+// frame that panicked rather than in the runtime's panic machinery.
+func panicStack() errors.StackTrace {
+	pcs := make([]uintptr, 64)
+	pcs = pcs[:runtime.Callers(2, pcs)]
+	for i, pc := range pcs {
+		if fn := runtime.FuncForPC(pc - 1); fn != nil && fn.Name() == "runtime.gopanic" {
+			pcs = pcs[i+1:]
+			break
+		}
+	}
+	for len(pcs) > 0 {
+		fn := runtime.FuncForPC(pcs[0] - 1)
+		if fn == nil || !strings.HasPrefix(fn.Name(), "runtime.") {
+			break
+		}
+		pcs = pcs[1:]
+	}
+	st := make(errors.StackTrace, len(pcs))
+	for i, pc := range pcs {
+		st[i] = errors.Frame(pc)
+	}
+	return st
+}
+
+// logAt logs at any level, keeping the caller's source for loggers from
+// this package.
+func logAt(lg Slogger, level Level, msg string) {
+	if esl, ok := lg.(*entrySlogger); ok {
+		esl.log(level, msg)
+		return
+	}
+	lg.Log(level, msg)
+}
+
+func logPanic(lg Slogger, value interface{}, opts RecoverOptions, fields Fields) {
+	pe := &panicError{value: value, stack: panicStack()}
+	src := "???:1"
+	if len(pe.stack) > 0 {
+		src = fmt.Sprintf("%s:%d", pe.stack[0], pe.stack[0])
+	}
+	f := Fields{"goroutine": goid(), "panic": pe.Error()}
+	for k, v := range fields {
+		f[k] = v
+	}
+	level := ErrorLevel
+	if opts.Fatal {
+		level = FatalLevel
+	}
+	logAt(lg.WithSource(src).WithError(pe).WithFields(f), level, "panic: "+pe.Error())
+}
+
+// RecoverAndLog recovers a panic and logs it as a single entry with the
+// panic value, goroutine id and full stack, through the Slogger carried by
+// ctx. It must be deferred directly:
+//
+//	defer slog.RecoverAndLog(ctx, slog.RecoverOptions{})
+func RecoverAndLog(ctx context.Context, opts RecoverOptions) {
+	value := recover()
+	if value == nil {
+		return
+	}
+	logPanic(FromContext(ctx), value, opts, nil)
+	if opts.Exit {
+		exit(2)
+	}
+	if opts.Repanic {
+		panic(value)
+	}
+}
+
+// RecoverHandler logs panics from next with the request method, path and
+// remote address, and replies with a 500 unless next had already started
+// its response. http.ErrAbortHandler is passed through silently.
+func RecoverHandler(next http.Handler, opts RecoverOptions) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw := &statusWriter{ResponseWriter: w}
+		defer func() {
+			value := recover()
+			if value == nil {
+				return
+			}
+			if value == http.ErrAbortHandler {
+				panic(value)
+			}
+			logPanic(FromContext(r.Context()), value, opts, Fields{
+				"method":      r.Method,
+				"path":        r.URL.Path,
+				"remote_addr": r.RemoteAddr,
+			})
+			if opts.Exit {
+				exit(2)
+			}
+			if opts.Repanic {
+				panic(value)
+			}
+			if sw.status == 0 {
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}
+		}()
+		next.ServeHTTP(sw.wrap(), r)
+	})
+}
@@ -0,0 +1,108 @@
+package slog
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func panicky() {
+	panic("boom")
+}
+
+func TestRecoverAndLog(t *testing.T) {
+	lg, lw := testSlog()
+	ctx := NewContext(context.Background(), lg.WithFields(Fields{"request_id": "r1"}))
+	func() {
+		defer RecoverAndLog(ctx, RecoverOptions{Fatal: true})
+		panicky()
+	}()
+	lastLine, _ := lw.LastLine()
+	if !strings.HasPrefix(lastLine, "F") {
+		t.Errorf("not fatal: %s", lastLine)
+	}
+	for _, want := range []string{"recover_test.go:12] panic: boom", `"request_id":"r1"`,
+		`"goroutine":`, `slog.panicky`} {
+		if !strings.Contains(lastLine, want) {
+			t.Errorf("missing %s: %s", want, lastLine)
+		}
+	}
+	if strings.Contains(lastLine, "runtime.gopanic") {
+		t.Errorf("stack includes panic machinery: %s", lastLine)
+	}
+}
+
+func TestRecoverHandler(t *testing.T) {
+	lg, lw := testSlog()
+	h := RecoverHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panicky()
+	}), RecoverOptions{})
+	req := httptest.NewRequest("GET", "/crash", nil)
+	req = req.WithContext(NewContext(req.Context(), lg))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status %d", rec.Code)
+	}
+	lastLine, _ := lw.LastLine()
+	if !strings.HasPrefix(lastLine, "E") {
+		t.Errorf("not error: %s", lastLine)
+	}
+	for _, want := range []string{`"path":"/crash"`, `"method":"GET"`} {
+		if !strings.Contains(lastLine, want) {
+			t.Errorf("missing %s: %s", want, lastLine)
+		}
+	}
+}
+
+func TestRecoverHandlerAfterWrite(t *testing.T) {
+	lg, lw := testSlog()
+	for name, handler := range map[string]http.HandlerFunc{
+		"header": func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusAccepted)
+			panicky()
+		},
+		"body": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("partial"))
+			panicky()
+		},
+	} {
+		req := httptest.NewRequest("GET", "/"+name, nil)
+		req = req.WithContext(NewContext(req.Context(), lg))
+		rec := httptest.NewRecorder()
+		RecoverHandler(handler, RecoverOptions{}).ServeHTTP(rec, req)
+		if rec.Code == http.StatusInternalServerError || strings.Contains(rec.Body.String(), "Internal Server Error") {
+			t.Errorf("%s: 500 sent after the response started: %d %q", name, rec.Code, rec.Body.String())
+		}
+		if lastLine, _ := lw.LastLine(); !strings.Contains(lastLine, `"path":"/`+name+`"`) {
+			t.Errorf("%s: panic not logged: %s", name, lastLine)
+		}
+	}
+}
+
+func TestRecoverHandlerHijack(t *testing.T) {
+	lg, _ := testSlog()
+	h := RecoverHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hj, ok := w.(http.Hijacker)
+		if !ok {
+			t.Fatal("Hijacker hidden")
+		}
+		hj.Hijack()
+		panicky()
+	}), RecoverOptions{})
+	req := httptest.NewRequest("GET", "/ws", nil)
+	req = req.WithContext(NewContext(req.Context(), lg))
+	rec := &hijackRecorder{ResponseRecorder: httptest.NewRecorder()}
+	h.ServeHTTP(rec, req)
+	if !rec.hijacked || rec.Body.Len() != 0 {
+		t.Errorf("500 sent on a hijacked connection: hijacked %v, body %q", rec.hijacked, rec.Body.String())
+	}
+
+	RecoverHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := w.(http.Hijacker); ok {
+			t.Error("Hijacker added")
+		}
+	}), RecoverOptions{}).ServeHTTP(httptest.NewRecorder(), req)
+}
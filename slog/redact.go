@@ -0,0 +1,173 @@
+package slog
+
+import (
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultMask replaces redacted values when RedactRules.Mask is empty.
+const DefaultMask = "[REDACTED]"
+
+// Common patterns for secrets and PII that tend to leak into logs.
+var (
+	BearerTokenRE = regexp.MustCompile(`(?i)bearer\s+[a-z0-9\-._~+/]+=*`)
+	EmailRE       = regexp.MustCompile(`[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}`)
+	CardNumberRE  = regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`)
+)
+
+// A Redactor supplies a loggable stand-in for itself. Types that carry
+// secrets can implement this and be logged safely in Fields.
+type Redactor interface {
+	Redact() interface{}
+}
+
+// RedactRules describe which values a redacting handler masks.
+type RedactRules struct {
+	// Keys are field names to mask entirely. Each is either an exact name
+	// or a glob in path.Match syntax, compared case-insensitively.
+	Keys []string
+	// Values mask any matching substring of a string value or the message.
+	Values []*regexp.Regexp
+	// Mask replaces redacted data; defaults to DefaultMask.
+	Mask string
+}
+
+func (rr *RedactRules) matchKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, pat := range rr.Keys {
+		if pat == key {
+			return true
+		}
+		if ok, _ := path.Match(pat, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (rr *RedactRules) redactString(s string) string {
+	for _, re := range rr.Values {
+		s = re.ReplaceAllLiteralString(s, rr.Mask)
+	}
+	return s
+}
+
+func (rr *RedactRules) redactValue(v interface{}) interface{} {
+	switch x := v.(type) {
+	case Redactor:
+		return x.Redact()
+	case Lazy:
+		return Lazy(func() interface{} { return rr.redactValue(x()) })
+	case string:
+		return rr.redactString(x)
+	case Fields:
+		return rr.redactFields(x)
+	case map[string]interface{}:
+		return map[string]interface{}(rr.redactFields(Fields(x)))
+	case map[string]string:
+		m := make(map[string]string, len(x))
+		for k, v := range x {
+			if rr.matchKey(k) {
+				m[k] = rr.Mask
+			} else {
+				m[k] = rr.redactString(v)
+			}
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(x))
+		for i, v := range x {
+			l[i] = rr.redactValue(v)
+		}
+		return l
+	case []string:
+		l := make([]string, len(x))
+		for i, v := range x {
+			l[i] = rr.redactString(v)
+		}
+		return l
+	case error:
+		return rr.redactString(x.Error())
+	}
+	return v
+}
+
+// redactFields returns a redacted copy; the input is never modified.
+func (rr *RedactRules) redactFields(f Fields) Fields {
+	if f == nil {
+		return nil
+	}
+	mf := make(Fields, len(f))
+	for k, v := range f {
+		if rr.matchKey(k) {
+			mf[k] = rr.Mask
+		} else {
+			mf[k] = rr.redactValue(v)
+		}
+	}
+	return mf
+}
+
+// redactedError masks the text of an error but keeps its stack trace.
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (re *redactedError) Error() string {
+	return re.msg
+}
+
+func (re *redactedError) StackTrace() errors.StackTrace {
+	if st, ok := re.err.(stackTracer); ok {
+		return st.StackTrace()
+	}
+	return nil
+}
+
+type redactingHandler struct {
+	h     Handler
+	rules RedactRules
+}
+
+func (rh *redactingHandler) Enabled(level Level, logger string) bool {
+	return handlerEnabled(rh.h, level, logger)
+}
+
+func (rh *redactingHandler) wrappedHandlers() []Handler {
+	return []Handler{rh.h}
+}
+
+func (rh *redactingHandler) WriteEntry(e Entry) error {
+	msg := rh.rules.redactString(e.Message())
+	fields := rh.rules.redactFields(e.Fields())
+	err := e.Err()
+	if err != nil {
+		if errMsg := rh.rules.redactString(err.Error()); errMsg != err.Error() {
+			err = &redactedError{errMsg, err}
+		}
+	}
+	return rh.h.WriteEntry(&EntryView{
+		Entry:       e,
+		MessageFunc: func() string { return msg },
+		FieldsFunc:  func() Fields { return fields },
+		ErrFunc:     func() error { return err },
+	})
+}
+
+// NewRedactingHandler masks secrets in each entry before passing it to h.
+// Entries are rewritten as copies, so the caller's Fields are never mutated.
+func NewRedactingHandler(h Handler, rules RedactRules) Handler {
+	if rules.Mask == "" {
+		rules.Mask = DefaultMask
+	}
+	keys := make([]string, len(rules.Keys))
+	for i, k := range rules.Keys {
+		keys[i] = strings.ToLower(k)
+	}
+	rules.Keys = keys
+	return &redactingHandler{h: h, rules: rules}
+}
@@ -0,0 +1,49 @@
+package slog
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+type apiKey string
+
+func (k apiKey) Redact() interface{} {
+	return "key-" + string(k)[:2] + "..."
+}
+
+func TestRedactingHandler(t *testing.T) {
+	cfg := &Config{}
+	lw := &lineWriter{}
+	h := NewRedactingHandler(NewHandler(lw, JsonFmtEntry), RedactRules{
+		Keys:   []string{"password", "*_token"},
+		Values: []*regexp.Regexp{BearerTokenRE, EmailRE},
+	})
+	slog := &slogger{h: NewLevelHandler(h, cfg), cfg: cfg}
+
+	fields := Fields{
+		"password":      "hunter2",
+		"Refresh_Token": "r3fr3sh",
+		"key":           apiKey("s3cr3t"),
+		"nested": map[string]interface{}{
+			"auth":     "Bearer abc.def",
+			"password": "hunter3",
+		},
+	}
+	slog.WithFields(fields).WithError(fmt.Errorf("denied for bob@example.com")).
+		Info("contact alice@example.com")
+	lastLine, _ := lw.LastLine()
+
+	for _, secret := range []string{"hunter2", "hunter3", "r3fr3sh", "s3cr3t", "abc.def", "alice@", "bob@"} {
+		if strings.Contains(lastLine, secret) {
+			t.Errorf("secret %q present in redacted output: %s", secret, lastLine)
+		}
+	}
+	if !strings.Contains(lastLine, "key-s3...") {
+		t.Errorf("Redactor value not used: %s", lastLine)
+	}
+	if fields["password"] != "hunter2" {
+		t.Errorf("caller fields mutated: %v", fields)
+	}
+}
@@ -0,0 +1,197 @@
+package slog
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// JsonSchema selects the key names and layout of JSON output, to suit
+// the log platform that ingests it.
+type JsonSchema int
+
+const (
+	// DefaultSchema uses the PascalCase keys of entry.MarshalJSON, which
+	// ParseJsonEntry reads back.
+	DefaultSchema JsonSchema = iota
+	// EcsSchema follows the Elastic Common Schema.
+	EcsSchema
+	// GcpSchema follows the structured logging format of Google Cloud
+	// Logging.
+	GcpSchema
+	// OtelSchema follows the OpenTelemetry log data model.
+	OtelSchema
+	maxJsonSchemas
+)
+
+var jsonSchemaName = [maxJsonSchemas]string{
+	"default",
+	"ecs",
+	"gcp",
+	"otel",
+}
+
+func (js *JsonSchema) Set(val string) error {
+	for i, name := range jsonSchemaName {
+		if strings.EqualFold(name, val) {
+			*js = JsonSchema(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid json schema: %s", val)
+}
+
+func (js *JsonSchema) String() string {
+	if *js < 0 || *js >= maxJsonSchemas {
+		return fmt.Sprintf("JsonSchema(%d)", int(*js))
+	}
+	return jsonSchemaName[int(*js)]
+}
+
+// FmtEntry encodes e as a line of JSON in this schema.
+func (js JsonSchema) FmtEntry(e Entry) string {
+	var doc map[string]interface{}
+	switch js {
+	case EcsSchema:
+		doc = ecsDoc(e)
+	case GcpSchema:
+		doc = gcpDoc(e)
+	case OtelSchema:
+		doc = otelDoc(e)
+	default:
+		return JsonFmtEntry(e)
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		data, _ = json.Marshal(map[string]string{"JsonErr": err.Error()})
+	}
+	return string(append(data, '\n'))
+}
+
+// splitSource splits "file.go:42" into its file and line.
+func splitSource(src string) (string, int) {
+	i := strings.LastIndexByte(src, ':')
+	if i < 0 {
+		return src, 0
+	}
+	line, _ := strconv.Atoi(src[i+1:])
+	return src[:i], line
+}
+
+// addFields copies user fields into doc without replacing schema keys.
+func addFields(doc map[string]interface{}, f Fields) {
+	for k, v := range f {
+		if _, ok := doc[k]; !ok {
+			doc[k] = v
+		}
+	}
+}
+
+const ecsVersion = "1.6.0"
+
+func ecsDoc(e Entry) map[string]interface{} {
+	file, line := splitSource(e.Source())
+	level := e.Level()
+	doc := map[string]interface{}{
+		"@timestamp":           e.Timestamp(),
+		"log.level":            level.String(),
+		"message":              e.Message(),
+		"log.origin.file.name": file,
+		"log.origin.file.line": line,
+		"process.pid":          e.Pid(),
+		"host.hostname":        e.Hostname(),
+		"ecs.version":          ecsVersion,
+	}
+	if err := e.Err(); err != nil {
+		doc["error.message"] = err.Error()
+	}
+	if stack := entryStackText(e); len(stack) > 0 {
+		doc["error.stack_trace"] = strings.Join(stack, "\n")
+	}
+	addFields(doc, e.Fields())
+	return doc
+}
+
+// gcpSeverity maps custom levels between Info and Warn to NOTICE and
+// those above Fatal to ALERT.
+func gcpSeverity(l Level) string {
+	switch {
+	case l < InfoLevel:
+		return "DEBUG"
+	case l == InfoLevel:
+		return "INFO"
+	case l < WarnLevel:
+		return "NOTICE"
+	case l < ErrorLevel:
+		return "WARNING"
+	case l < FatalLevel:
+		return "ERROR"
+	case l == FatalLevel:
+		return "CRITICAL"
+	}
+	return "ALERT"
+}
+
+func gcpDoc(e Entry) map[string]interface{} {
+	file, line := splitSource(e.Source())
+	doc := map[string]interface{}{
+		"timestamp": e.Timestamp(),
+		"severity":  gcpSeverity(e.Level()),
+		"message":   e.Message(),
+		"logging.googleapis.com/sourceLocation": map[string]string{
+			"file": file,
+			"line": strconv.Itoa(line),
+		},
+		"hostname": e.Hostname(),
+		"pid":      e.Pid(),
+	}
+	if err := e.Err(); err != nil {
+		doc["error"] = err.Error()
+	}
+	if stack := entryStackText(e); len(stack) > 0 {
+		doc["stack_trace"] = strings.Join(stack, "\n")
+	}
+	addFields(doc, e.Fields())
+	return doc
+}
+
+// otelSeverityNumber relies on levels being spaced four apart, as OTel
+// severities are: DEBUG is 5, INFO 9 and so on, and a level below Debug
+// lands in TRACE.
+func otelSeverityNumber(l Level) int {
+	n := 5 + int(l-DebugLevel)
+	if n < 1 {
+		return 1
+	} else if n > 24 {
+		return 24
+	}
+	return n
+}
+
+func otelDoc(e Entry) map[string]interface{} {
+	file, line := splitSource(e.Source())
+	level := e.Level()
+	attrs := map[string]interface{}{
+		"code.filepath": file,
+		"code.lineno":   line,
+	}
+	if err := e.Err(); err != nil {
+		attrs["exception.message"] = err.Error()
+	}
+	if stack := entryStackText(e); len(stack) > 0 {
+		attrs["exception.stacktrace"] = strings.Join(stack, "\n")
+	}
+	addFields(attrs, e.Fields())
+	return map[string]interface{}{
+		"Timestamp":      strconv.FormatInt(e.Timestamp().UnixNano(), 10),
+		"SeverityText":   strings.ToUpper(level.String()),
+		"SeverityNumber": otelSeverityNumber(level),
+		"Body":           e.Message(),
+		"Resource": map[string]interface{}{
+			"host.name":   e.Hostname(),
+			"process.pid": e.Pid(),
+		},
+		"Attributes": attrs,
+	}
+}
@@ -0,0 +1,60 @@
+package slog
+
+import (
+	"errors"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite golden files in testdata")
+
+func TestJsonSchemaGolden(t *testing.T) {
+	eb := NewEntry(ErrorLevel, "write failed").
+		SetTimestamp(time.Date(2015, 7, 27, 16, 22, 0, 123456000, time.UTC)).
+		SetSource("store.go:42").
+		SetPid(1234).
+		SetHostname("db1").
+		SetFields(Fields{"user": "bob", "bytes": 512}).
+		SetErr(errors.New("disk full"))
+	eb.stack = []string{"main.write store.go:42", "main.main main.go:10"}
+
+	for i := DefaultSchema; i < maxJsonSchemas; i++ {
+		schema := i
+		fname := filepath.Join("testdata", "schema_"+schema.String()+".json")
+		got := schema.FmtEntry(eb)
+		if *updateGolden {
+			if err := ioutil.WriteFile(fname, []byte(got), 0666); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		want, err := ioutil.ReadFile(fname)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != string(want) {
+			t.Errorf("%s schema:\ngot  %s\nwant %s", schema.String(), got, want)
+		}
+	}
+}
+
+func TestConfigJsonSchema(t *testing.T) {
+	cfg := &Config{Format: JsonFormat}
+	if err := cfg.JsonSchema.Set("GCP"); err != nil {
+		t.Fatal(err)
+	}
+	lw := &lineWriter{}
+	h := NewFormatHandler(lw, cfg)
+	h.WriteEntry(NewEntry(WarnLevel, "hi"))
+	lastLine, _ := lw.LastLine()
+	if want := `"severity":"WARNING"`; !strings.Contains(lastLine, want) {
+		t.Errorf("missing %s: %s", want, lastLine)
+	}
+	if err := cfg.JsonSchema.Set("splunk"); err == nil {
+		t.Error("accepted unknown schema")
+	}
+}
@@ -0,0 +1,69 @@
+package slog
+
+import "sync/atomic"
+
+// Scope attaches fields to everything logged through it until Unbind, for
+// code without a context.Context to carry a request-scoped Slogger. The
+// scope is an explicit value to pass along, not goroutine-local state.
+//
+//	sc := slog.Bind(slog.Fields{"request_id": id})
+//	defer sc.Unbind()
+//	sc.Info("started")
+type Scope struct {
+	Slogger
+	base    Slogger
+	parent  *Scope
+	fields  Fields
+	unbound int32
+}
+
+func newScope(base Slogger, parent *Scope, f Fields) *Scope {
+	sc := &Scope{base: base, parent: parent, fields: mergeFields(nil, f)}
+	sc.Slogger = base.WithFielder(sc)
+	return sc
+}
+
+// Bind returns a Scope logging to the default logger with fields.
+func Bind(fields Fields) *Scope {
+	return newScope(std, nil, fields)
+}
+
+// BindLogger returns a Scope logging to lg with fields.
+func BindLogger(lg Slogger, fields Fields) *Scope {
+	return newScope(lg, nil, fields)
+}
+
+// Bind returns a nested Scope that adds fields to those of sc.
+func (sc *Scope) Bind(fields Fields) *Scope {
+	return newScope(sc.base, sc, fields)
+}
+
+// Unbind drops the fields of sc from later entries, including those of
+// scopes nested in sc, which keep only their own fields. Sloggers derived
+// from sc with WithFields keep the fields they were created with.
+func (sc *Scope) Unbind() {
+	atomic.StoreInt32(&sc.unbound, 1)
+}
+
+// Fields returns the fields currently bound by sc and its parents.
+func (sc *Scope) Fields() Fields {
+	var f Fields
+	if sc.parent != nil {
+		f = sc.parent.Fields()
+	}
+	if atomic.LoadInt32(&sc.unbound) != 0 {
+		return f
+	}
+	return mergeFields(f, sc.fields)
+}
+
+// Go runs fn on a new goroutine with a Scope holding the fields sc has
+// bound now. Unbinding sc later does not affect it; it is unbound when fn
+// returns.
+func Go(sc *Scope, fn func(sc *Scope)) {
+	child := newScope(sc.base, nil, sc.Fields())
+	go func() {
+		defer child.Unbind()
+		fn(child)
+	}()
+}
@@ -0,0 +1,42 @@
+package slog
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScope(t *testing.T) {
+	slog, lw := testSlog()
+	req := BindLogger(slog, Fields{"request_id": "r1"})
+	step := req.Bind(Fields{"step": "parse"})
+
+	step.Info("parsing")
+	lastLine, _ := lw.LastLine()
+	if !strings.Contains(lastLine, `"request_id":"r1","step":"parse"`) {
+		t.Errorf("nested scope fields missing: %s", lastLine)
+	}
+
+	done := make(chan struct{})
+	Go(step, func(sc *Scope) {
+		defer close(done)
+		req.Unbind()
+		sc.Info("worker")
+	})
+	<-done
+	lastLine, _ = lw.LastLine()
+	if !strings.Contains(lastLine, `"request_id":"r1","step":"parse"`) {
+		t.Errorf("worker did not inherit scope: %s", lastLine)
+	}
+
+	step.Info("after unbind")
+	lastLine, _ = lw.LastLine()
+	if strings.Contains(lastLine, "request_id") || !strings.Contains(lastLine, `"step":"parse"`) {
+		t.Errorf("parent fields not unbound: %s", lastLine)
+	}
+	step.Unbind()
+	step.Info("all unbound")
+	lastLine, _ = lw.LastLine()
+	if !strings.Contains(lastLine, "| {}") {
+		t.Errorf("fields remain after unbind: %s", lastLine)
+	}
+}
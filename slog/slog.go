@@ -45,7 +45,25 @@ func init() {
 
 type Config struct {
 	Level Level
-	Fname string
+	// Levels overrides Level for loggers created by Named.
+	Levels NameLevels
+	Format Format
+	// Key names used by JsonFormat.
+	JsonSchema JsonSchema
+	Fname      string
+	// Outputs replaces Fname when set. Each is a file path, "stdout",
+	// "stderr" or "stdstreams", which splits entries between stdout and
+	// stderr at WarnLevel.
+	Outputs []string
+	// Queue up to this many entries and write them in the background.
+	AsyncQueue int
+	Rotate     RotateConfig
+	// Durability of file outputs.
+	Sync         SyncPolicy
+	SyncInterval time.Duration
+	// Metrics, if set, counts the entries, bytes and drops of the handler
+	// tree built by Setup and NewConfigHandler.
+	Metrics *Metrics
 }
 
 // Register the flags on the default logger.
@@ -55,7 +73,10 @@ func RegisterDefaultFlags() {
 
 func RegisterFlags(fs *flag.FlagSet, cfg *Config) {
 	fs.Var(&cfg.Level, "log.level", "logs at or above this threshold")
+	fs.Var(&cfg.Levels, "log.levels", "per logger thresholds, e.g. db=debug,http=warn")
 	fs.StringVar(&cfg.Fname, "log.file", "/dev/stderr", "direct logs to this file")
+	fs.Var(&cfg.Format, "log.fmt", "log format: auto, console, glog, json or binary")
+	fs.Var(&cfg.JsonSchema, "log.json-schema", "json key names: default, ecs, gcp or otel")
 }
 
 type logHandler struct {
@@ -68,21 +89,24 @@ func (h *logHandler) WriteEntry(e Entry) error {
 	data := h.fmtEntry(e)
 	h.mu.Lock()
 	defer h.mu.Unlock()
-	_, err := io.WriteString(h.wr, data)
+	_, err := io.WriteString(stdioWriter(h.wr), data)
 	return err
 }
 
+// GlogFmtEntry formats e on one line in the style of glog. Newlines, pipes
+// and backslashes in the message are escaped as \n, \| and \\, so the
+// first " | " always ends the message.
 func GlogFmtEntry(e Entry) string {
 	dateTime := e.Timestamp().Format("0102 15:04:05")
 	micros := e.Timestamp().Nanosecond() / 1e3
 
-	levelName := levelChar[e.Level()]
+	levelName := levelChar(e.Level())
 
 	fm := Fields{}
 	if e.Err() != nil {
 		fm["Err"] = e.Err().Error()
 	}
-	if st := e.StackTrace(); st != nil {
+	if st := entryStack(e); st != nil {
 		fm["StackTrace"] = st
 	}
 	if fields := e.Fields(); len(fields) > 0 {
@@ -105,9 +129,14 @@ func GlogFmtEntry(e Entry) string {
 	}
 
 	return fmt.Sprintf("%c%s.%06d %d %s] %s | %s\n",
-		levelName, dateTime, micros, pid, e.Source(), e.Message(), data)
+		levelName, dateTime, micros, pid, e.Source(), glogEscaper.Replace(e.Message()), data)
 }
 
+// glogEscaper keeps each glog entry on one line and its message free of
+// the field separator. Backslashes are escaped too so ParseGlogEntry can
+// undo it exactly.
+var glogEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\r", `\r`, "|", `\|`)
+
 // Allow override for testing.
 var now = time.Now
 
@@ -118,49 +147,68 @@ type slogger struct {
 }
 
 func (lg *slogger) Info(args ...interface{}) {
-	esl := entrySlogger{handler: lg.h}
+	esl := entrySlogger{handler: lg}
 	esl.log(InfoLevel, fmt.Sprint(args...))
 }
 
 func (lg *slogger) Infof(format string, args ...interface{}) {
-	esl := entrySlogger{handler: lg.h}
+	esl := entrySlogger{handler: lg}
 	esl.log(InfoLevel, fmt.Sprintf(format, args...))
 }
 
 func (lg *slogger) Warn(args ...interface{}) {
-	esl := entrySlogger{handler: lg.h}
+	esl := entrySlogger{handler: lg}
 	esl.log(WarnLevel, fmt.Sprint(args...))
 }
 
 func (lg *slogger) Warnf(format string, args ...interface{}) {
-	esl := entrySlogger{handler: lg.h}
+	esl := entrySlogger{handler: lg}
 	esl.log(WarnLevel, fmt.Sprintf(format, args...))
 }
 
 func (lg *slogger) Error(args ...interface{}) {
-	esl := entrySlogger{handler: lg.h}
+	esl := entrySlogger{handler: lg}
 	esl.log(ErrorLevel, fmt.Sprint(args...))
 }
 
 func (lg *slogger) Errorf(format string, args ...interface{}) {
-	esl := entrySlogger{handler: lg.h}
+	esl := entrySlogger{handler: lg}
 	esl.log(ErrorLevel, fmt.Sprintf(format, args...))
 }
 
+func (lg *slogger) Log(level Level, args ...interface{}) {
+	esl := entrySlogger{handler: lg}
+	esl.log(level, fmt.Sprint(args...))
+}
+
+func (lg *slogger) Logf(level Level, format string, args ...interface{}) {
+	esl := entrySlogger{handler: lg}
+	esl.log(level, fmt.Sprintf(format, args...))
+}
+
 func (lg *slogger) WithSource(src string) Slogger {
-	return &entrySlogger{entry{source: src}, lg.h}
+	return &entrySlogger{entry{source: src}, lg}
 }
 
 func (lg *slogger) WithError(err error) Slogger {
-	return &entrySlogger{entry{err: err}, lg.h}
+	return &entrySlogger{entry{err: err}, lg}
 }
 
 func (lg *slogger) WithFields(f Fields) Slogger {
-	return &entrySlogger{entry{fields: f}, lg.h}
+	return &entrySlogger{entry{fields: f}, lg}
 }
 
 func (lg *slogger) WithFielder(f Fielder) Slogger {
-	return &entrySlogger{entry{fielders: []Fielder{f}}, lg.h}
+	return &entrySlogger{entry{fielders: []Fielder{f}}, lg}
+}
+
+// WriteEntry passes e to the current handler. Sloggers derived from lg
+// hold lg rather than its handler so they follow SetHandler.
+func (lg *slogger) WriteEntry(e Entry) error {
+	lg.mu.Lock()
+	h := lg.h
+	lg.mu.Unlock()
+	return h.WriteEntry(e)
 }
 
 func source(depth int) (string, int) {
@@ -190,17 +238,37 @@ type LevelHandler struct {
 	cfg *Config
 }
 
+// minLevel returns the threshold for the named logger.
+func (lh *LevelHandler) minLevel(logger string) Level {
+	if logger != "" && len(lh.cfg.Levels) > 0 {
+		if level, ok := lh.cfg.Levels.level(logger); ok {
+			return level
+		}
+	}
+	return lh.cfg.Level
+}
+
 func (lh *LevelHandler) WriteEntry(e Entry) error {
-	if e.Level() < lh.cfg.Level {
+	if e.Level() < lh.minLevel(loggerName(e.Fields())) {
 		return nil
 	}
 	return lh.h.WriteEntry(e)
 }
 
+// Enabled reports whether level passes the threshold for logger, which is
+// Config.Level unless Config.Levels sets one for the name.
+func (lh *LevelHandler) Enabled(level Level, logger string) bool {
+	return level >= lh.minLevel(logger) && handlerEnabled(lh.h, level, logger)
+}
+
+func (lh *LevelHandler) wrappedHandlers() []Handler {
+	return []Handler{lh.h}
+}
+
 func new(wr io.Writer) *slogger {
 	cfg := &Config{}
 	return &slogger{
-		h:   NewLevelHandler(NewHandler(wr, GlogFmtEntry), cfg),
+		h:   NewLevelHandler(NewFormatHandler(wr, cfg), cfg),
 		cfg: cfg,
 	}
 }
@@ -214,10 +282,13 @@ var (
 	Warn        = std.Warn
 	Errorf      = std.Errorf
 	Error       = std.Error
+	Logf        = std.Logf
+	Log         = std.Log
 	WithFields  = std.WithFields
 	WithFielder = std.WithFielder
 	WithError   = std.WithError
 	WithSource  = std.WithSource
+	Named       = std.Named
 )
 
 func SetHandler(h Handler) {
@@ -106,3 +106,32 @@ func TestFileLine(t *testing.T) {
 		t.Fatalf("file name %s not present in direct slog output, missing stack?: %s", tokenSource, lastLine)
 	}
 }
+
+func TestStdLogger(t *testing.T) {
+	slog, lw := testSlog()
+	l := NewStdLogger(InfoLevel, slog)
+
+	l.Printf("plain")
+	lastLine, _ := lw.LastLine()
+	if !strings.Contains(lastLine, "slog_test.go:") || !strings.Contains(lastLine, "] plain |") {
+		t.Errorf("bad plain line: %q", lastLine)
+	}
+
+	l.SetPrefix("[db] ")
+	l.SetFlags(log.Ldate | log.Lmicroseconds | log.Lshortfile)
+	l.Printf("first\nsecond")
+	lastLine, _ = lw.LastLine()
+	if !strings.Contains(lastLine, "slog_test.go:") || !strings.Contains(lastLine, `] first\nsecond |`) {
+		t.Errorf("bad multi-line entry: %q", lastLine)
+	}
+	if len(lw.lines) != 2 {
+		t.Errorf("multi-line message split: %q", lw.lines)
+	}
+
+	l.SetFlags(log.Ltime | log.Lmsgprefix)
+	l.Printf("with msgprefix")
+	lastLine, _ = lw.LastLine()
+	if !strings.Contains(lastLine, "] with msgprefix |") {
+		t.Errorf("bad msgprefix line: %q", lastLine)
+	}
+}
@@ -0,0 +1,93 @@
+package slog
+
+import (
+	"io"
+	"os"
+	"sync"
+)
+
+type splitHandler struct {
+	low, high Handler
+	threshold Level
+}
+
+func (sh *splitHandler) WriteEntry(e Entry) error {
+	if e.Level() >= sh.threshold {
+		return sh.high.WriteEntry(e)
+	}
+	return sh.low.WriteEntry(e)
+}
+
+func (sh *splitHandler) Enabled(level Level, logger string) bool {
+	if level >= sh.threshold {
+		return handlerEnabled(sh.high, level, logger)
+	}
+	return handlerEnabled(sh.low, level, logger)
+}
+
+func (sh *splitHandler) wrappedHandlers() []Handler {
+	return []Handler{sh.low, sh.high}
+}
+
+// NewSplitHandler sends entries at or above threshold to high and the rest
+// to low.
+func NewSplitHandler(low, high Handler, threshold Level) Handler {
+	return &splitHandler{low: low, high: high, threshold: threshold}
+}
+
+// NewStdStreamsHandler writes entries below WarnLevel to stdout and the
+// rest to stderr, as container log collectors expect. See
+// NewAtomicLineHandler for how lines are kept whole.
+func NewStdStreamsHandler(fmtEntry FmtEntry) Handler {
+	return NewSplitHandler(
+		NewAtomicLineHandler(os.Stdout, fmtEntry),
+		NewAtomicLineHandler(os.Stderr, fmtEntry),
+		WarnLevel)
+}
+
+// fileLocks serializes writes to each file across all the handlers that
+// share it, such as two handlers built for os.Stderr.
+var fileLocks = struct {
+	sync.Mutex
+	m map[*os.File]*sync.Mutex
+}{m: make(map[*os.File]*sync.Mutex)}
+
+func fileLock(f *os.File) *sync.Mutex {
+	fileLocks.Lock()
+	defer fileLocks.Unlock()
+	mu := fileLocks.m[f]
+	if mu == nil {
+		mu = &sync.Mutex{}
+		fileLocks.m[f] = mu
+	}
+	return mu
+}
+
+// atomicLineHandler writes each entry whole, with one write, while holding
+// the lock for its file.
+type atomicLineHandler struct {
+	mu       *sync.Mutex
+	wr       io.Writer
+	fmtEntry FmtEntry
+}
+
+// NewAtomicLineHandler writes each entry to f with a single write, holding
+// a lock shared by every handler on f, so entries from this process are
+// never interleaved. Entries are never split or truncated.
+//
+// Other processes writing to the same pipe are a different matter: the
+// kernel only keeps writes of up to PIPE_BUF bytes (4096 on Linux, 512 on
+// some systems) from interleaving. A longer entry can have another
+// process's output spliced into it.
+func NewAtomicLineHandler(f *os.File, fmtEntry FmtEntry) Handler {
+	return &atomicLineHandler{mu: fileLock(f), wr: f, fmtEntry: fmtEntry}
+}
+
+func (alh *atomicLineHandler) WriteEntry(e Entry) error {
+	data := alh.fmtEntry(e)
+	wr := stdioWriter(alh.wr)
+	alh.mu.Lock()
+	defer alh.mu.Unlock()
+	_, err := io.WriteString(wr, data)
+	return err
+}
@@ -0,0 +1,68 @@
+package slog
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSplitHandler(t *testing.T) {
+	low, high := &lineWriter{}, &lineWriter{}
+	cfg := &Config{}
+	h := NewSplitHandler(NewHandler(low, GlogFmtEntry), NewHandler(high, GlogFmtEntry), WarnLevel)
+	slog := &slogger{h: h, cfg: cfg}
+	slog.Info("low")
+	slog.Warn("high")
+	slog.Error("higher")
+	if len(low.lines) != 1 || len(high.lines) != 2 {
+		t.Fatalf("misrouted entries: low %v high %v", low.lines, high.lines)
+	}
+}
+
+func TestAtomicLinesOnPipe(t *testing.T) {
+	rd, wr, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgFmt := func(e Entry) string {
+		return e.Message() + "\n"
+	}
+	// Entries well over PIPE_BUF, from separate handlers on one pipe, stay
+	// whole and unsplit.
+	const size = 64 << 10
+	wg := sync.WaitGroup{}
+	for _, c := range []string{"a", "b", "é"} {
+		lg := &slogger{h: NewAtomicLineHandler(wr, msgFmt), cfg: &Config{}}
+		msg := strings.Repeat(c, size/len(c))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				lg.Info(msg)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		wr.Close()
+	}()
+
+	scanner := bufio.NewScanner(rd)
+	scanner.Buffer(make([]byte, 2*size), 2*size)
+	lines := 0
+	for scanner.Scan() {
+		line := scanner.Text()
+		if len(line) != size || strings.Trim(line, line[:1]) != "" && strings.Trim(line, "é") != "" {
+			t.Fatalf("split or interleaved line: %.40q...", line)
+		}
+		lines++
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if lines != 3*20 {
+		t.Errorf("expected %d lines, found %d", 3*20, lines)
+	}
+}
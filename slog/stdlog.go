@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"fmt"
 	stdLog "log"
+	"runtime"
 	"strconv"
+	"strings"
 )
 
 // CopyStandardLogTo arranges for messages written to the Go "log" package's
@@ -21,37 +23,104 @@ func CopyStandardLogTo(name string) {
 	// Set a log format that captures the user's file and line:
 	//   d.go:23: message
 	stdLog.SetFlags(stdLog.Lshortfile)
-	stdLog.SetOutput(logBridge(sev))
+	stdLog.SetOutput(&logBridge{level: sev, lg: std, settings: func() (string, int) {
+		return stdLog.Prefix(), stdLog.Flags()
+	}})
 }
 
-// logBridge provides the Write method that enables CopyStandardLogTo to connect
-// Go's standard logs to the logs provided by this package.
-type logBridge Level
-
-// Write parses the standard logging line and passes its components to the
-// logger for severity(lb).
-// FIXME(msolo) log messages with newlines should be encoded to prevent hijacking the overall
-// log format. I suppose the real solution is to use JSON or binary logs.
-func (lb logBridge) Write(b []byte) (n int, err error) {
-	var (
-		file = "???"
-		line = 1
-		text string
-	)
-	b = bytes.TrimSpace(b)
-	// Split "d.go:23: message" into "d.go", "23", and "message".
-	if parts := bytes.SplitN(b, []byte{':'}, 3); len(parts) != 3 || len(parts[0]) < 1 || len(parts[2]) < 1 {
-		text = fmt.Sprintf("bad log format: %s", b)
-	} else {
-		file = string(parts[0])
-		text = string(parts[2][1:]) // skip leading space
-		line, err = strconv.Atoi(string(parts[1]))
-		if err != nil {
-			text = fmt.Sprintf("bad line number: %s", b)
-			line = 1
+// NewStdLogger returns a *log.Logger for APIs that require one, such as
+// http.Server.ErrorLog, that logs each message through lg at level. The
+// logger's prefix and flags may be changed freely.
+func NewStdLogger(level Level, lg Slogger) *stdLog.Logger {
+	lb := &logBridge{level: level, lg: lg}
+	l := stdLog.New(lb, "", 0)
+	lb.settings = func() (string, int) {
+		return l.Prefix(), l.Flags()
+	}
+	return l
+}
+
+// logBridge provides the Write method that connects a *log.Logger to a
+// Slogger.
+type logBridge struct {
+	level Level
+	lg    Slogger
+	// Returns the current prefix and flags of the writing logger.
+	settings func() (string, int)
+}
+
+// Write strips the header the writing logger added according to its prefix
+// and flags, and logs the rest as a single entry, even if it spans lines.
+// The source comes from the Lshortfile or Llongfile header when present
+// and from the caller of the logger otherwise.
+func (lb *logBridge) Write(b []byte) (n int, err error) {
+	prefix, flags := lb.settings()
+	text := string(bytes.TrimSuffix(b, []byte{'\n'}))
+
+	if flags&stdLog.Lmsgprefix == 0 {
+		text = strings.TrimPrefix(text, prefix)
+	}
+	if flags&stdLog.Ldate != 0 {
+		text = trimField(text, len("2006/01/02"))
+	}
+	if flags&(stdLog.Ltime|stdLog.Lmicroseconds) != 0 {
+		width := len("15:04:05")
+		if flags&stdLog.Lmicroseconds != 0 {
+			width = len("15:04:05.000000")
+		}
+		text = trimField(text, width)
+	}
+	src := ""
+	if flags&(stdLog.Lshortfile|stdLog.Llongfile) != 0 {
+		// Split "d.go:23: message" into "d.go", "23", and "message".
+		if i := strings.Index(text, ": "); i > 0 {
+			fileLine := text[:i]
+			if j := strings.LastIndexByte(fileLine, ':'); j > 0 {
+				if line, err := strconv.Atoi(fileLine[j+1:]); err == nil {
+					file := fileLine[:j]
+					if slash := strings.LastIndex(file, "/"); slash >= 0 {
+						file = file[slash+1:]
+					}
+					src = fmt.Sprintf("%s:%d", file, line)
+					text = text[i+2:]
+				}
+			}
+		}
+	}
+	if flags&stdLog.Lmsgprefix != 0 {
+		text = strings.TrimPrefix(text, prefix)
+	}
+	if src == "" {
+		src = logCaller()
+	}
+	logAt(lb.lg.WithSource(src), lb.level, text)
+	return len(b), nil
+}
+
+// trimField removes a fixed width field and the space after it.
+func trimField(text string, width int) string {
+	if len(text) > width && text[width] == ' ' {
+		return text[width+1:]
+	}
+	return text
+}
+
+// logCaller returns the source of the first caller outside the log
+// package and this bridge.
+func logCaller() string {
+	pcs := make([]uintptr, 16)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, "log.") {
+			file := frame.File
+			if slash := strings.LastIndex(file, "/"); slash >= 0 {
+				file = file[slash+1:]
+			}
+			return fmt.Sprintf("%s:%d", file, frame.Line)
+		}
+		if !more {
+			return "???:1"
 		}
 	}
-	src := fmt.Sprintf("%s:%d", file, line)
-	std.WithSource(src).(*entrySlogger).log(Level(lb), string(text))
-	return len(text), nil
 }
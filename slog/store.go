@@ -0,0 +1,544 @@
+package slog
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// A Store is a Handler that appends entries to JSON segment files in a
+// directory. Each segment has a sidecar index of fixed-size records
+// holding the time, level, source and location of every entry, so queries
+// can skip entries without decoding them.
+//
+// Index timestamps never decrease, so a query seeks to its time range
+// instead of scanning every segment. An entry written after a later one,
+// say by a slow goroutine, is indexed at the time of the entry before it.
+//
+//	seg-00000001.log  one JSON entry per line
+//	seg-00000001.idx  indexRecordSize bytes per entry
+type Store struct {
+	mu   sync.Mutex
+	dir  string
+	opts StoreOptions
+	seq  int
+	seg  *os.File
+	idx  *os.File
+	size int64
+	// The latest indexed timestamp.
+	lastTs int64
+}
+
+type StoreOptions struct {
+	// Start a new segment once the current one exceeds this size.
+	// Defaults to 64MiB.
+	SegmentBytes int64
+	// Remove the oldest segments beyond this count; 0 keeps them all.
+	MaxSegments int
+}
+
+const (
+	defaultSegmentBytes = 64 << 20
+	// timestamp(8) offset(8) length(4) level(1) pad(3) source hash(8)
+	indexRecordSize = 32
+)
+
+type indexRecord struct {
+	timestamp  int64
+	offset     int64
+	length     uint32
+	level      Level
+	sourceHash uint64
+}
+
+func (ir *indexRecord) marshal(b []byte) {
+	binary.LittleEndian.PutUint64(b[0:], uint64(ir.timestamp))
+	binary.LittleEndian.PutUint64(b[8:], uint64(ir.offset))
+	binary.LittleEndian.PutUint32(b[16:], ir.length)
+	level := ir.level
+	// Unregistered levels may not fit; clamping keeps queries by minimum
+	// level working.
+	if level < minRegisteredLevel {
+		level = minRegisteredLevel
+	} else if level > maxRegisteredLevel {
+		level = maxRegisteredLevel
+	}
+	b[20] = byte(int8(level))
+	binary.LittleEndian.PutUint64(b[24:], ir.sourceHash)
+}
+
+func (ir *indexRecord) unmarshal(b []byte) {
+	ir.timestamp = int64(binary.LittleEndian.Uint64(b[0:]))
+	ir.offset = int64(binary.LittleEndian.Uint64(b[8:]))
+	ir.length = binary.LittleEndian.Uint32(b[16:])
+	ir.level = Level(int8(b[20]))
+	ir.sourceHash = binary.LittleEndian.Uint64(b[24:])
+}
+
+// Sources are indexed by file name so a query can select a whole file.
+func sourceHash(src string) uint64 {
+	h := fnv.New64a()
+	io.WriteString(h, sourceFile(src))
+	return h.Sum64()
+}
+
+func readIndexRecord(idx *os.File, i int64) (indexRecord, error) {
+	b := make([]byte, indexRecordSize)
+	ir := indexRecord{}
+	if _, err := idx.ReadAt(b, i*indexRecordSize); err != nil {
+		return ir, err
+	}
+	ir.unmarshal(b)
+	return ir, nil
+}
+
+func segmentName(dir string, seq int, ext string) string {
+	return filepath.Join(dir, fmt.Sprintf("seg-%08d%s", seq, ext))
+}
+
+// segments returns the sequence numbers of the segments in dir, oldest
+// first.
+func segments(dir string) ([]int, error) {
+	names, err := filepath.Glob(filepath.Join(dir, "seg-*.log"))
+	if err != nil {
+		return nil, err
+	}
+	seqs := make([]int, 0, len(names))
+	for _, name := range names {
+		var seq int
+		if _, err := fmt.Sscanf(filepath.Base(name), "seg-%08d.log", &seq); err == nil {
+			seqs = append(seqs, seq)
+		}
+	}
+	sort.Ints(seqs)
+	return seqs, nil
+}
+
+// OpenStore opens or creates a store in dir.
+func OpenStore(dir string, opts StoreOptions) (*Store, error) {
+	if opts.SegmentBytes <= 0 {
+		opts.SegmentBytes = defaultSegmentBytes
+	}
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return nil, err
+	}
+	seqs, err := segments(dir)
+	if err != nil {
+		return nil, err
+	}
+	st := &Store{dir: dir, opts: opts, seq: 1}
+	if len(seqs) > 0 {
+		st.seq = seqs[len(seqs)-1]
+	}
+	if err := st.openSegment(); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+func (st *Store) openSegment() error {
+	seg, err := os.OpenFile(segmentName(st.dir, st.seq, ".log"), os.O_CREATE|os.O_RDWR|os.O_APPEND, 0666)
+	if err != nil {
+		return err
+	}
+	idx, err := os.OpenFile(segmentName(st.dir, st.seq, ".idx"), os.O_CREATE|os.O_RDWR|os.O_APPEND, 0666)
+	if err != nil {
+		seg.Close()
+		return err
+	}
+	segInfo, err := seg.Stat()
+	if err == nil {
+		err = st.repairIndex(idx, segInfo.Size())
+	}
+	if err != nil {
+		seg.Close()
+		idx.Close()
+		return err
+	}
+	st.seg, st.idx, st.size = seg, idx, segInfo.Size()
+	return nil
+}
+
+// repairIndex drops a torn trailing index record, or records pointing past
+// the end of the segment, left by a crash between the two writes.
+func (st *Store) repairIndex(idx *os.File, segSize int64) error {
+	fi, err := idx.Stat()
+	if err != nil {
+		return err
+	}
+	n := fi.Size() / indexRecordSize
+	for ; n > 0; n-- {
+		ir, err := readIndexRecord(idx, n-1)
+		if err != nil {
+			return err
+		}
+		if ir.offset+int64(ir.length) <= segSize {
+			if ir.timestamp > st.lastTs {
+				st.lastTs = ir.timestamp
+			}
+			break
+		}
+	}
+	if n*indexRecordSize == fi.Size() {
+		return nil
+	}
+	return idx.Truncate(n * indexRecordSize)
+}
+
+func (st *Store) rollSegment() error {
+	if err := st.closeSegment(); err != nil {
+		return err
+	}
+	st.seq++
+	if err := st.openSegment(); err != nil {
+		return err
+	}
+	if st.opts.MaxSegments <= 0 {
+		return nil
+	}
+	seqs, err := segments(st.dir)
+	if err != nil {
+		return err
+	}
+	for len(seqs) > st.opts.MaxSegments {
+		os.Remove(segmentName(st.dir, seqs[0], ".log"))
+		os.Remove(segmentName(st.dir, seqs[0], ".idx"))
+		seqs = seqs[1:]
+	}
+	return nil
+}
+
+func (st *Store) closeSegment() error {
+	err := st.seg.Close()
+	if closeErr := st.idx.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func (st *Store) WriteEntry(e Entry) error {
+	data := JsonFmtEntry(e)
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.size > 0 && st.size+int64(len(data)) > st.opts.SegmentBytes {
+		if err := st.rollSegment(); err != nil {
+			return err
+		}
+	}
+
+	ts := e.Timestamp().UnixNano()
+	if ts < st.lastTs {
+		ts = st.lastTs
+	}
+	st.lastTs = ts
+	ir := indexRecord{
+		timestamp:  ts,
+		offset:     st.size,
+		length:     uint32(len(data)),
+		level:      e.Level(),
+		sourceHash: sourceHash(e.Source()),
+	}
+	if _, err := io.WriteString(st.seg, data); err != nil {
+		return err
+	}
+	st.size += int64(len(data))
+	b := make([]byte, indexRecordSize)
+	ir.marshal(b)
+	_, err := st.idx.Write(b)
+	return err
+}
+
+func (st *Store) Close() error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.closeSegment()
+}
+
+// StoreQuery selects entries from a Store. Zero values match everything.
+type StoreQuery struct {
+	From, To time.Time
+	MinLevel Level
+	// Source matches the file part of an entry's source, like "main.go".
+	Source  string
+	FieldEq map[string]string
+}
+
+func (q *StoreQuery) matchIndex(ir *indexRecord, srcHash uint64) bool {
+	if !q.From.IsZero() && ir.timestamp < q.From.UnixNano() {
+		return false
+	}
+	if !q.To.IsZero() && ir.timestamp >= q.To.UnixNano() {
+		return false
+	}
+	if ir.level < q.MinLevel {
+		return false
+	}
+	return q.Source == "" || ir.sourceHash == srcHash
+}
+
+func (q *StoreQuery) matchEntry(e Entry) bool {
+	if q.Source != "" && sourceFile(e.Source()) != q.Source {
+		return false
+	}
+	if len(q.FieldEq) == 0 {
+		return true
+	}
+	fields := e.Fields()
+	for k, v := range q.FieldEq {
+		fv, ok := fields[k]
+		if !ok || fmt.Sprint(fv) != v {
+			return false
+		}
+	}
+	return true
+}
+
+// Query returns the entries logged in [from, to) at or above minLevel
+// whose fields equal those in fieldEq, oldest first.
+func (st *Store) Query(from, to time.Time, minLevel Level, fieldEq map[string]string) (*EntryIterator, error) {
+	return st.Search(StoreQuery{From: from, To: to, MinLevel: minLevel, FieldEq: fieldEq})
+}
+
+// Search returns the entries matching q, oldest first. Entries written
+// after the call are not returned.
+func (st *Store) Search(q StoreQuery) (*EntryIterator, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	seqs, err := segments(st.dir)
+	if err != nil {
+		return nil, err
+	}
+	idxInfo, err := st.idx.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return newEntryIterator(st.dir, q, seqs, st.seq, idxInfo.Size()), nil
+}
+
+// SearchDir queries the store in dir without opening it for writing, so it
+// is safe to use while another process logs to it.
+func SearchDir(dir string, q StoreQuery) (*EntryIterator, error) {
+	seqs, err := segments(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(seqs) == 0 {
+		return nil, fmt.Errorf("no log segments in %s", dir)
+	}
+	return newEntryIterator(dir, q, seqs, seqs[len(seqs)-1], -1), nil
+}
+
+func newEntryIterator(dir string, q StoreQuery, seqs []int, lastSeq int, lastLen int64) *EntryIterator {
+	var srcHash uint64
+	if q.Source != "" {
+		srcHash = sourceHash(q.Source)
+	}
+	return &EntryIterator{
+		dir:     dir,
+		q:       q,
+		srcHash: srcHash,
+		seqs:    seqs,
+		lastSeq: lastSeq,
+		lastLen: lastLen,
+	}
+}
+
+// EntryIterator walks the results of a query.
+//
+//	it, err := st.Query(from, to, slog.WarnLevel, nil)
+//	for it.Next() {
+//		fmt.Print(slog.GlogFmtEntry(it.Entry()))
+//	}
+//	err = it.Close()
+type EntryIterator struct {
+	dir     string
+	q       StoreQuery
+	srcHash uint64
+	seqs    []int
+	lastSeq int
+	lastLen int64 // index bytes to read from lastSeq; -1 reads to EOF
+
+	seg  *os.File
+	idx  *bufio.Reader
+	idxF *os.File
+	left int64
+	cur  Entry
+	err  error
+}
+
+func (it *EntryIterator) openNext() bool {
+	it.closeSegment()
+	for len(it.seqs) > 0 {
+		seq := it.seqs[0]
+		it.seqs = it.seqs[1:]
+		if seq > it.lastSeq {
+			continue
+		}
+		seg, err := os.Open(segmentName(it.dir, seq, ".log"))
+		if os.IsNotExist(err) {
+			// Removed by retention since the query started.
+			continue
+		} else if err != nil {
+			it.err = err
+			return false
+		}
+		idx, err := os.Open(segmentName(it.dir, seq, ".idx"))
+		if err != nil {
+			seg.Close()
+			it.err = err
+			return false
+		}
+		it.seg, it.idxF = seg, idx
+		if !it.seekRange(seq) {
+			it.closeSegment()
+			if it.err != nil {
+				return false
+			}
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// seekRange positions the index of the open segment at the first record
+// at or after q.From and limits reading to records before q.To. It reports
+// false if no records in the segment fall in the range.
+func (it *EntryIterator) seekRange(seq int) bool {
+	fi, err := it.idxF.Stat()
+	if err != nil {
+		it.err = err
+		return false
+	}
+	n := fi.Size() / indexRecordSize
+	// A concurrent writer may append to the last segment while we read.
+	unbounded := seq == it.lastSeq && it.lastLen < 0
+	if seq == it.lastSeq && it.lastLen >= 0 {
+		n = it.lastLen / indexRecordSize
+	}
+	if n == 0 && !unbounded {
+		return false
+	}
+
+	// at returns the timestamp of record i, remembering the first error.
+	at := func(i int) int64 {
+		ir, err := readIndexRecord(it.idxF, int64(i))
+		if err != nil && it.err == nil {
+			it.err = err
+		}
+		return ir.timestamp
+	}
+	start, end := int64(0), n
+	if n > 0 && !it.q.To.IsZero() {
+		to := it.q.To.UnixNano()
+		if at(0) >= to {
+			// Later segments are later still.
+			it.seqs = nil
+			return false
+		}
+		if at(int(n-1)) >= to {
+			end = int64(sort.Search(int(n), func(i int) bool { return at(i) >= to }))
+			unbounded = false
+		}
+	}
+	if n > 0 && !it.q.From.IsZero() {
+		from := it.q.From.UnixNano()
+		if at(int(n-1)) < from && !unbounded {
+			return false
+		}
+		start = int64(sort.Search(int(end), func(i int) bool { return at(i) >= from }))
+	}
+	if it.err != nil {
+		return false
+	}
+	if _, err := it.idxF.Seek(start*indexRecordSize, io.SeekStart); err != nil {
+		it.err = err
+		return false
+	}
+	it.idx = bufio.NewReader(it.idxF)
+	it.left = end - start
+	if unbounded {
+		it.left = -1
+	}
+	return true
+}
+
+func (it *EntryIterator) closeSegment() {
+	if it.seg != nil {
+		it.seg.Close()
+		it.idxF.Close()
+		it.seg, it.idxF, it.idx = nil, nil, nil
+	}
+}
+
+// Next advances to the next matching entry and reports whether there is
+// one.
+func (it *EntryIterator) Next() bool {
+	b := make([]byte, indexRecordSize)
+	for it.err == nil {
+		if it.seg == nil || it.left == 0 {
+			if !it.openNext() {
+				return false
+			}
+		}
+		if _, err := io.ReadFull(it.idx, b); err == io.EOF || err == io.ErrUnexpectedEOF {
+			it.left = 0
+			continue
+		} else if err != nil {
+			it.err = err
+			return false
+		}
+		if it.left > 0 {
+			it.left--
+		}
+
+		ir := indexRecord{}
+		ir.unmarshal(b)
+		if !it.q.matchIndex(&ir, it.srcHash) {
+			continue
+		}
+		data := make([]byte, ir.length)
+		if _, err := it.seg.ReadAt(data, ir.offset); err == io.EOF {
+			// A concurrent writer has not finished this entry.
+			it.left = 0
+			continue
+		} else if err != nil {
+			it.err = err
+			return false
+		}
+		e, err := ParseJsonEntry(data)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		if it.q.matchEntry(e) {
+			it.cur = e
+			return true
+		}
+	}
+	return false
+}
+
+// Entry returns the current entry.
+func (it *EntryIterator) Entry() Entry {
+	return it.cur
+}
+
+// Err returns the first error encountered while iterating.
+func (it *EntryIterator) Err() error {
+	return it.err
+}
+
+// Close releases the iterator's files and returns any iteration error.
+func (it *EntryIterator) Close() error {
+	it.closeSegment()
+	it.seqs = nil
+	return it.err
+}
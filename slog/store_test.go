@@ -0,0 +1,145 @@
+package slog
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestStoreQuery(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	st, err := OpenStore(tmpDir, StoreOptions{SegmentBytes: 512})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	cfg := &Config{}
+	slog := &slogger{h: st, cfg: cfg}
+	for i := 0; i < 10; i++ {
+		lg := slog.WithFields(Fields{"req": i % 2})
+		lg.Infof("info %d", i)
+		lg.Warnf("warn %d", i)
+	}
+	slog.WithSource("other.go:7").Warn("elsewhere")
+
+	seqs, _ := segments(tmpDir)
+	if len(seqs) < 2 {
+		t.Fatalf("expected several segments, found %v", seqs)
+	}
+
+	collect := func(it *EntryIterator, err error) []string {
+		if err != nil {
+			t.Fatal(err)
+		}
+		var msgs []string
+		for it.Next() {
+			msgs = append(msgs, it.Entry().Message())
+		}
+		if err := it.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return msgs
+	}
+
+	msgs := collect(st.Query(time.Time{}, time.Time{}, WarnLevel, map[string]string{"req": "1"}))
+	if len(msgs) != 5 || msgs[0] != "warn 1" || msgs[4] != "warn 9" {
+		t.Fatalf("unexpected results: %v", msgs)
+	}
+
+	msgs = collect(SearchDir(tmpDir, StoreQuery{Source: "other.go"}))
+	if len(msgs) != 1 || msgs[0] != "elsewhere" {
+		t.Fatalf("unexpected source results: %v", msgs)
+	}
+
+	msgs = collect(st.Query(fakeTime().Add(time.Second), time.Time{}, DebugLevel, nil))
+	if len(msgs) != 0 {
+		t.Fatalf("expected nothing after the fake time: %v", msgs)
+	}
+}
+
+func TestStoreTimeRange(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	st, err := OpenStore(tmpDir, StoreOptions{SegmentBytes: 1024})
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := fakeTime()
+	for i := 0; i < 30; i++ {
+		st.WriteEntry(NewEntry(InfoLevel, fmt.Sprintf("at %d", i)).SetTimestamp(base.Add(time.Duration(i) * time.Second)))
+	}
+	// Out of order, so indexed at the time of the entry before it.
+	st.WriteEntry(NewEntry(InfoLevel, "late").SetTimestamp(base.Add(5 * time.Second)))
+	if err := st.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if st, err = OpenStore(tmpDir, StoreOptions{SegmentBytes: 1024}); err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	st.WriteEntry(NewEntry(InfoLevel, "reopened").SetTimestamp(base))
+
+	seqs, _ := segments(tmpDir)
+	if len(seqs) < 4 {
+		t.Fatalf("expected several segments, found %v", seqs)
+	}
+
+	query := func(from, to time.Duration) []string {
+		it, err := st.Query(base.Add(from), base.Add(to), DebugLevel, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var msgs []string
+		for it.Next() {
+			msgs = append(msgs, it.Entry().Message())
+		}
+		if err := it.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return msgs
+	}
+
+	msgs := query(10*time.Second, 20*time.Second)
+	if len(msgs) != 10 || msgs[0] != "at 10" || msgs[9] != "at 19" {
+		t.Errorf("unexpected results: %v", msgs)
+	}
+	msgs = query(29*time.Second, time.Minute)
+	if len(msgs) != 3 || msgs[0] != "at 29" || msgs[1] != "late" || msgs[2] != "reopened" {
+		t.Errorf("unexpected results: %v", msgs)
+	}
+	if msgs = query(-time.Minute, -time.Second); len(msgs) != 0 {
+		t.Errorf("expected nothing before the first entry: %v", msgs)
+	}
+	if msgs = query(time.Minute, time.Hour); len(msgs) != 0 {
+		t.Errorf("expected nothing after the last entry: %v", msgs)
+	}
+}
+
+func TestIndexRecordLevelRange(t *testing.T) {
+	b := make([]byte, indexRecordSize)
+	for level, want := range map[Level]Level{
+		testTraceLevel: testTraceLevel,
+		FatalLevel:     FatalLevel,
+		Level(127):     Level(127),
+		Level(300):     Level(127),
+		Level(-1000):   Level(-128),
+	} {
+		(&indexRecord{level: level}).marshal(b)
+		ir := &indexRecord{}
+		ir.unmarshal(b)
+		if ir.level != want {
+			t.Errorf("level %d: stored as %d, want %d", level, ir.level, want)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package slog
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// EntryBuilder is a mutable Entry for handler authors who need to
+// construct or rewrite entries. Setters return the builder for chaining.
+type EntryBuilder struct {
+	entry
+	stack []string
+}
+
+// NewEntry returns an entry stamped with the current time, process and
+// the caller's source location.
+func NewEntry(level Level, message string) *EntryBuilder {
+	file, line := source(-1)
+	return &EntryBuilder{entry: entry{
+		timeStarted: now().UTC(),
+		level:       level,
+		source:      fmt.Sprintf("%s:%d", file, line),
+		message:     message,
+		pid:         pid,
+		hostname:    hostname,
+	}}
+}
+
+// CloneEntry copies e into a builder. Fields are copied, so changing them
+// does not affect e.
+func CloneEntry(e Entry) *EntryBuilder {
+	eb := &EntryBuilder{entry: entry{
+		timeStarted: e.Timestamp(),
+		level:       e.Level(),
+		source:      e.Source(),
+		message:     e.Message(),
+		fields:      mergeFields(nil, e.Fields()),
+		err:         e.Err(),
+		pid:         e.Pid(),
+		hostname:    e.Hostname(),
+	}}
+	if stx, ok := e.(stackTexter); ok {
+		eb.stack = stx.Stack()
+	}
+	return eb
+}
+
+func (eb *EntryBuilder) SetTimestamp(t time.Time) *EntryBuilder {
+	eb.timeStarted = t
+	return eb
+}
+
+func (eb *EntryBuilder) SetLevel(level Level) *EntryBuilder {
+	eb.level = level
+	return eb
+}
+
+func (eb *EntryBuilder) SetSource(src string) *EntryBuilder {
+	eb.source = src
+	return eb
+}
+
+func (eb *EntryBuilder) SetMessage(msg string) *EntryBuilder {
+	eb.message = msg
+	return eb
+}
+
+// SetFields replaces all fields.
+func (eb *EntryBuilder) SetFields(f Fields) *EntryBuilder {
+	eb.fields = f
+	eb.fielders = nil
+	return eb
+}
+
+func (eb *EntryBuilder) SetField(key string, value interface{}) *EntryBuilder {
+	eb.fields = eb.entry.Fields()
+	eb.fielders = nil
+	eb.fields[key] = value
+	return eb
+}
+
+func (eb *EntryBuilder) DeleteField(key string) *EntryBuilder {
+	eb.fields = eb.entry.Fields()
+	eb.fielders = nil
+	delete(eb.fields, key)
+	return eb
+}
+
+// SetErr sets the error, and with it the stack trace if err has one.
+func (eb *EntryBuilder) SetErr(err error) *EntryBuilder {
+	eb.err = err
+	return eb
+}
+
+func (eb *EntryBuilder) SetPid(pid int) *EntryBuilder {
+	eb.pid = pid
+	return eb
+}
+
+func (eb *EntryBuilder) SetHostname(hostname string) *EntryBuilder {
+	eb.hostname = hostname
+	return eb
+}
+
+// Stack returns stack frames carried over as text from a decoded entry.
+func (eb *EntryBuilder) Stack() []string {
+	return eb.stack
+}
+
+func (eb *EntryBuilder) MarshalJSON() ([]byte, error) {
+	return marshalEntryJSON(eb)
+}
+
+// EntryView presents an Entry with some accessors overridden. Each non-nil
+// func replaces the accessor of the same name; the rest pass through.
+//
+//	h.WriteEntry(&slog.EntryView{
+//		Entry:       e,
+//		MessageFunc: func() string { return strings.ToUpper(e.Message()) },
+//	})
+type EntryView struct {
+	Entry
+	TimestampFunc  func() time.Time
+	LevelFunc      func() Level
+	SourceFunc     func() string
+	MessageFunc    func() string
+	FieldsFunc     func() Fields
+	ErrFunc        func() error
+	StackTraceFunc func() errors.StackTrace
+}
+
+func (ev *EntryView) Timestamp() time.Time {
+	if ev.TimestampFunc != nil {
+		return ev.TimestampFunc()
+	}
+	return ev.Entry.Timestamp()
+}
+
+func (ev *EntryView) Level() Level {
+	if ev.LevelFunc != nil {
+		return ev.LevelFunc()
+	}
+	return ev.Entry.Level()
+}
+
+func (ev *EntryView) Source() string {
+	if ev.SourceFunc != nil {
+		return ev.SourceFunc()
+	}
+	return ev.Entry.Source()
+}
+
+func (ev *EntryView) Message() string {
+	if ev.MessageFunc != nil {
+		return ev.MessageFunc()
+	}
+	return ev.Entry.Message()
+}
+
+func (ev *EntryView) Fields() Fields {
+	if ev.FieldsFunc != nil {
+		return ev.FieldsFunc()
+	}
+	return ev.Entry.Fields()
+}
+
+func (ev *EntryView) Err() error {
+	if ev.ErrFunc != nil {
+		return ev.ErrFunc()
+	}
+	return ev.Entry.Err()
+}
+
+func (ev *EntryView) StackTrace() errors.StackTrace {
+	if ev.StackTraceFunc != nil {
+		return ev.StackTraceFunc()
+	}
+	return ev.Entry.StackTrace()
+}
+
+// Stack passes through text stack frames of a decoded entry.
+func (ev *EntryView) Stack() []string {
+	if stx, ok := ev.Entry.(stackTexter); ok {
+		return stx.Stack()
+	}
+	return nil
+}
+
+func (ev *EntryView) MarshalJSON() ([]byte, error) {
+	return marshalEntryJSON(ev)
+}
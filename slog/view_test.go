@@ -0,0 +1,26 @@
+package slog
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEntryBuilder(t *testing.T) {
+	eb := NewEntry(WarnLevel, "built").SetField("a", 1)
+	if !strings.HasPrefix(eb.Source(), "view_test.go:") {
+		t.Errorf("wrong source: %s", eb.Source())
+	}
+
+	clone := CloneEntry(eb).SetField("a", 2).SetMessage("cloned")
+	if eb.Fields()["a"] != 1 || eb.Message() != "built" {
+		t.Errorf("clone modified the original: %v %s", eb.Fields(), eb.Message())
+	}
+
+	view := &EntryView{Entry: clone, LevelFunc: func() Level { return ErrorLevel }}
+	data := JsonFmtEntry(view)
+	for _, want := range []string{`"Level":"error"`, `"Message":"cloned"`, `"a":2`} {
+		if !strings.Contains(data, want) {
+			t.Errorf("missing %s: %s", want, data)
+		}
+	}
+}
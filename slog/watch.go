@@ -0,0 +1,178 @@
+package slog
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"sync"
+	"time"
+)
+
+// ConfigPollInterval is how often a watched config is checked when file
+// notifications are unavailable.
+var ConfigPollInterval = 2 * time.Second
+
+// Editors tend to write a file in several steps; wait for them to settle.
+const configSettleDelay = 50 * time.Millisecond
+
+var errHandlerClosed = errors.New("slog: handler closed")
+
+// reloadHandler is one generation of a watched handler tree. Closing waits
+// for in-flight writes; stragglers that arrive afterward are passed on to
+// whichever generation replaced it.
+type reloadHandler struct {
+	mu     sync.RWMutex
+	h      Handler
+	closer io.Closer
+	closed bool
+	cw     *ConfigWatcher
+}
+
+func (rh *reloadHandler) WriteEntry(e Entry) error {
+	rh.mu.RLock()
+	if rh.closed {
+		rh.mu.RUnlock()
+		if next := rh.cw.current(); next != rh {
+			return next.WriteEntry(e)
+		}
+		return errHandlerClosed
+	}
+	defer rh.mu.RUnlock()
+	return rh.h.WriteEntry(e)
+}
+
+func (rh *reloadHandler) Enabled(level Level, logger string) bool {
+	rh.mu.RLock()
+	defer rh.mu.RUnlock()
+	return handlerEnabled(rh.h, level, logger)
+}
+
+func (rh *reloadHandler) Close() error {
+	rh.mu.Lock()
+	defer rh.mu.Unlock()
+	if rh.closed {
+		return nil
+	}
+	rh.closed = true
+	return rh.closer.Close()
+}
+
+// ConfigWatcher keeps the default handler in sync with a config file.
+type ConfigWatcher struct {
+	fname string
+	data  []byte
+
+	mu  sync.Mutex
+	cur *reloadHandler
+
+	notify notifier
+	done   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// A notifier signals that a watched file may have changed.
+type notifier interface {
+	C() <-chan struct{}
+	Close() error
+}
+
+// WatchConfig loads fname, installs the handler tree it describes with
+// SetHandler and rebuilds it whenever the file changes. Invalid configs are
+// logged and ignored, leaving the previous tree in place.
+func WatchConfig(fname string) (*ConfigWatcher, error) {
+	cw := &ConfigWatcher{fname: fname, done: make(chan struct{})}
+	if err := cw.reload(); err != nil {
+		return nil, err
+	}
+
+	n, err := newInotifier(fname)
+	if err != nil {
+		n = newPoller(fname, ConfigPollInterval)
+	}
+	cw.notify = n
+
+	cw.wg.Add(1)
+	go cw.run()
+	return cw, nil
+}
+
+func (cw *ConfigWatcher) current() *reloadHandler {
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+	return cw.cur
+}
+
+func (cw *ConfigWatcher) run() {
+	defer cw.wg.Done()
+	for {
+		select {
+		case <-cw.done:
+			return
+		case <-cw.notify.C():
+		}
+		// Collapse a burst of notifications into one reload.
+		timer := time.NewTimer(configSettleDelay)
+	settle:
+		for {
+			select {
+			case <-cw.done:
+				timer.Stop()
+				return
+			case <-cw.notify.C():
+			case <-timer.C:
+				break settle
+			}
+		}
+		if err := cw.reload(); err != nil {
+			WithError(err).Errorf("log config %s rejected, keeping previous config", cw.fname)
+		}
+	}
+}
+
+// reload swaps in a new handler tree if the file content changed.
+func (cw *ConfigWatcher) reload() error {
+	data, err := ioutil.ReadFile(cw.fname)
+	if err != nil {
+		return err
+	}
+	if cw.cur != nil && bytes.Equal(data, cw.data) {
+		return nil
+	}
+	cfg, err := parseConfig(cw.fname, data)
+	if err != nil {
+		return err
+	}
+	h, closer, err := NewConfigHandler(cfg)
+	if err != nil {
+		return err
+	}
+
+	rh := &reloadHandler{h: h, closer: closer, cw: cw}
+	cw.mu.Lock()
+	old := cw.cur
+	cw.cur = rh
+	cw.data = data
+	cw.mu.Unlock()
+
+	SetHandler(rh)
+	if old != nil {
+		return old.Close()
+	}
+	return nil
+}
+
+// Close stops watching and closes the installed handler tree. The default
+// handler is left pointing at the closed tree, so install another one
+// first if logging continues.
+func (cw *ConfigWatcher) Close() error {
+	close(cw.done)
+	err := cw.notify.Close()
+	cw.wg.Wait()
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+	if closeErr := cw.cur.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,75 @@
+package slog
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+// inotifier watches the directory holding a file, since editors and
+// config management usually replace files by renaming over them.
+type inotifier struct {
+	f    *os.File
+	name string
+	c    chan struct{}
+}
+
+func newInotifier(fname string) (notifier, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+	mask := uint32(syscall.IN_CLOSE_WRITE | syscall.IN_MOVED_TO | syscall.IN_CREATE | syscall.IN_DELETE)
+	if _, err := syscall.InotifyAddWatch(fd, filepath.Dir(fname), mask); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+	// A non-blocking fd lets the runtime poller wake Read on Close.
+	in := &inotifier{
+		f:    os.NewFile(uintptr(fd), "inotify"),
+		name: filepath.Base(fname),
+		c:    make(chan struct{}, 1),
+	}
+	go in.run()
+	return in, nil
+}
+
+func (in *inotifier) run() {
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		n, err := in.f.Read(buf)
+		if err != nil {
+			return
+		}
+		for off := 0; off+syscall.SizeofInotifyEvent <= n; {
+			ev := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[off]))
+			nameBytes := buf[off+syscall.SizeofInotifyEvent : off+syscall.SizeofInotifyEvent+int(ev.Len)]
+			off += syscall.SizeofInotifyEvent + int(ev.Len)
+			if trimNul(nameBytes) != in.name {
+				continue
+			}
+			select {
+			case in.c <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+func trimNul(b []byte) string {
+	for i, c := range b {
+		if c == 0 {
+			return string(b[:i])
+		}
+	}
+	return string(b)
+}
+
+func (in *inotifier) C() <-chan struct{} {
+	return in.c
+}
+
+func (in *inotifier) Close() error {
+	return in.f.Close()
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+// +build !linux
+
+package slog
+
+import "errors"
+
+func newInotifier(fname string) (notifier, error) {
+	return nil, errors.New("inotify not supported")
+}
@@ -0,0 +1,58 @@
+package slog
+
+import (
+	"os"
+	"time"
+)
+
+// poller notices changes by comparing the size and mtime of a file.
+type poller struct {
+	fname string
+	c     chan struct{}
+	done  chan struct{}
+}
+
+func newPoller(fname string, interval time.Duration) *poller {
+	p := &poller{fname: fname, c: make(chan struct{}, 1), done: make(chan struct{})}
+	go p.run(interval)
+	return p
+}
+
+func (p *poller) stat() (size int64, mtime time.Time) {
+	fi, err := os.Stat(p.fname)
+	if err != nil {
+		return -1, time.Time{}
+	}
+	return fi.Size(), fi.ModTime()
+}
+
+func (p *poller) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	size, mtime := p.stat()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+		}
+		newSize, newMtime := p.stat()
+		if newSize == size && newMtime.Equal(mtime) {
+			continue
+		}
+		size, mtime = newSize, newMtime
+		select {
+		case p.c <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (p *poller) C() <-chan struct{} {
+	return p.c
+}
+
+func (p *poller) Close() error {
+	close(p.done)
+	return nil
+}
@@ -0,0 +1,64 @@
+package slog
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func waitForFile(t *testing.T, fname, substr string) {
+	for i := 0; i < 100; i++ {
+		data, _ := ioutil.ReadFile(fname)
+		if strings.Contains(string(data), substr) {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("%q never appeared in %s", substr, fname)
+}
+
+func TestWatchConfig(t *testing.T) {
+	defer SetHandler(GetHandler())
+
+	tmpDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	fname := filepath.Join(tmpDir, "log.json")
+	logA := filepath.Join(tmpDir, "a.log")
+	logB := filepath.Join(tmpDir, "b.log")
+	writeConfig := func(data string) {
+		if err := ioutil.WriteFile(fname, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeConfig(`{"outputs": ["` + logA + `"]}`)
+	cw, err := WatchConfig(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cw.Close()
+
+	lg := WithSource("watch.go:1")
+	lg.Info("first generation")
+	waitForFile(t, logA, "first generation")
+
+	first := cw.current()
+	writeConfig(`{"outputs": ["` + logB + `"]}`)
+	for i := 0; i < 100 && cw.current() == first; i++ {
+		time.Sleep(20 * time.Millisecond)
+	}
+	lg.Info("second generation")
+	waitForFile(t, logB, "second generation")
+
+	writeConfig(`{"level": "loud"}`)
+	waitForFile(t, logB, "rejected")
+	lg.Info("still second generation")
+	waitForFile(t, logB, "still second generation")
+}